@@ -7,6 +7,9 @@ export const useOllamaStore = defineStore('ollama', () => {
   const version = ref('')
   const upgrade = ref(false)
   const lastVersion = ref({})
+  const latency = ref(0)
+  const runningModels = ref([])
+  const lastError = ref('')
 
-  return { installed, started, canStart, version }
+  return { installed, started, canStart, version, upgrade, lastVersion, latency, runningModels, lastError }
 })
@@ -45,11 +45,18 @@ func (a *App) startup(ctx context.Context) {
 	// Let's assume 'app' is the instance used by Wails.
 	// If 'app' is globally defined as `var app = App{}`, we can initialize systemApp like this:
 	if a.systemApp == nil {
-		a.systemApp = NewSystemApp()
+		a.systemApp = &systemApp
 	}
 
 	dao.startup(ctx)
-	job.GetSchedule().AddFunc("0/10 * * * * ?", ollama.Heartbeat)
+	job.GetSchedule().AddFunc("0/30 * * * * ?", profiles.heartbeat)
+	job.GetSchedule().AddFunc("0/5 * * * * ?", runningModels.sampleJob)
+	job.GetSchedule().AddFunc("0 0 0/6 * * ?", models.checkUpdatesJob)
+	job.GetSchedule().AddFunc("0 0/10 * * * ?", onlineCache.refreshJob)
+	wailsruntime.EventsOn(ctx, eventModelRefresh, func(...interface{}) {
+		modelCapabilities.clearDigests()
+	})
+	go ollama.monitor(ctx)
 	go a.checkUpgrade()
 }
 
@@ -108,6 +115,7 @@ func (a *App) domReady(ctx context.Context) {
 
 func (a *App) shutdown(ctx context.Context) {
 	log.Info().Msg("Ollama Desktop shutdown...")
+	serve.stop()
 	dao.shutdown()
 	job.GetSchedule().Stop()
 }
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
+	ollama2 "ollama-desktop/internal/ollama/ollama"
+	"time"
+)
+
+const (
+	eventOnlineStale = "online_stale"
+	// 后台刷新时仅处理最近访问过的页面
+	cacheRefreshWindow = 24 * time.Hour
+	cacheRefreshLimit  = 20
+	// 超过该时间未访问的页面将被清理
+	cacheRetention = 30 * 24 * time.Hour
+)
+
+var onlineCache = OnlineCache{}
+
+// OnlineCache 基于 SQLite 的 ollama.com 页面缓存
+type OnlineCache struct {
+}
+
+func (c *OnlineCache) Get(ctx context.Context, url string) (*ollama2.Page, error) {
+	sqlStr := `select url, body, etag, last_modified, ttl, fetched_at, expires_at from t_online_cache where url = ?`
+	rows, err := dao.db().QueryContext(ctx, sqlStr, url)
+	if err != nil {
+		log.Error().Err(err).Msg("query online cache error")
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, nil
+	}
+	page, err := c.scanPage(rows)
+	if err != nil {
+		log.Error().Err(err).Msg("fill online cache error")
+		return nil, err
+	}
+	if _, err := dao.db().ExecContext(ctx, `update t_online_cache set accessed_at = ? where url = ?`, time.Now(), url); err != nil {
+		log.Warn().Err(err).Msg("update online cache access time")
+	}
+	return page, nil
+}
+
+func (c *OnlineCache) Put(ctx context.Context, page *ollama2.Page) error {
+	sqlStr := `insert or replace into t_online_cache(url, body, etag, last_modified, ttl, fetched_at, expires_at, accessed_at)
+               values (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := dao.db().ExecContext(ctx, sqlStr, page.Url, string(page.Body), page.ETag, page.LastModified,
+		int64(page.TTL/time.Second), page.FetchedAt, page.ExpiresAt, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msg("save online cache error")
+	}
+	return err
+}
+
+func (c *OnlineCache) scanPage(rows *sql.Rows) (*ollama2.Page, error) {
+	page := &ollama2.Page{}
+	var body string
+	var ttl int64
+	if err := rows.Scan(&page.Url, &body, &page.ETag, &page.LastModified, &ttl, &page.FetchedAt, &page.ExpiresAt); err != nil {
+		return nil, err
+	}
+	page.Body = []byte(body)
+	page.TTL = time.Duration(ttl) * time.Second
+	return page, nil
+}
+
+// 使用过期缓存时通知前端
+func (c *OnlineCache) onStale(page *ollama2.Page, err error) {
+	log.Warn().Err(err).Str("url", page.Url).Time("fetchedAt", page.FetchedAt).Msg("serve stale online page")
+	runtime.EventsEmit(app.ctx, eventOnlineStale, map[string]any{
+		"url":       page.Url,
+		"fetchedAt": page.FetchedAt,
+		"error":     err.Error(),
+	})
+}
+
+// 后台刷新最近访问且已过期的页面，并清理长期未访问的页面
+func (c *OnlineCache) refreshJob() {
+	now := time.Now()
+	if _, err := dao.db().ExecContext(app.ctx, `delete from t_online_cache where accessed_at < ?`, now.Add(-cacheRetention)); err != nil {
+		log.Warn().Err(err).Msg("purge online cache")
+	}
+
+	sqlStr := `select url, body, etag, last_modified, ttl, fetched_at, expires_at
+            from t_online_cache
+            where expires_at < ? and accessed_at > ?
+            order by accessed_at desc
+            limit ?`
+	rows, err := dao.db().QueryContext(app.ctx, sqlStr, now, now.Add(-cacheRefreshWindow), cacheRefreshLimit)
+	if err != nil {
+		log.Warn().Err(err).Msg("query expired online cache")
+		return
+	}
+	var pages []*ollama2.Page
+	for rows.Next() {
+		page, err := c.scanPage(rows)
+		if err != nil {
+			log.Warn().Err(err).Msg("fill online cache")
+			break
+		}
+		pages = append(pages, page)
+	}
+	rows.Close()
+
+	client := ollama.newOllamaClient()
+	for _, page := range pages {
+		if err := client.Refresh(app.ctx, page); err != nil {
+			log.Warn().Err(err).Str("url", page.Url).Msg("refresh online cache")
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"ollama-desktop/internal/log"
+	olm "ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/api"
+	"ollama-desktop/internal/ollama/capability"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 发送消息前获取模型能力的超时时间，避免服务无响应时阻塞发送
+	capabilityTimeout = 5 * time.Second
+	// 模型名称与摘要对应关系的缓存时间，本地模型变化时立即清除
+	modelDigestTTL = time.Minute
+)
+
+var modelCapabilities = ModelCapabilities{}
+
+// ModelCapabilities 按摘要缓存的模型能力，摘要相同的模型内容相同
+type ModelCapabilities struct {
+	cache   map[string]*capability.Model
+	digests map[string]*digestCache // 按服务地址缓存模型名称与摘要的对应关系
+	lock    sync.Mutex
+}
+
+type digestCache struct {
+	names     map[string]string
+	fetchedAt time.Time
+}
+
+func (d *digestCache) lookup(name string) string {
+	if digest := d.names[name]; digest != "" {
+		return digest
+	}
+	return d.names[name+":latest"]
+}
+
+// 清除模型名称与摘要的缓存，本地模型变化（eventModelRefresh）时调用
+func (m *ModelCapabilities) clearDigests() {
+	m.lock.Lock()
+	m.digests = nil
+	m.lock.Unlock()
+}
+
+// 模型摘要，缓存过期或找不到模型时重新获取模型列表
+func (m *ModelCapabilities) digest(ctx context.Context, client *api.Client, name string) (string, error) {
+	server := client.Base.String()
+	m.lock.Lock()
+	cached := m.digests[server]
+	m.lock.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < modelDigestTTL {
+		if digest := cached.lookup(name); digest != "" {
+			return digest, nil
+		}
+	}
+	list, err := client.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	cached = &digestCache{names: make(map[string]string, len(list.Models)), fetchedAt: time.Now()}
+	for _, model := range list.Models {
+		cached.names[model.Model] = model.Digest
+		cached.names[model.Name] = model.Digest
+	}
+	m.lock.Lock()
+	if m.digests == nil {
+		m.digests = make(map[string]*digestCache)
+	}
+	m.digests[server] = cached
+	m.lock.Unlock()
+	if digest := cached.lookup(name); digest != "" {
+		return digest, nil
+	}
+	return "", fmt.Errorf("model %s not found", name)
+}
+
+func (m *ModelCapabilities) get(ctx context.Context, client *api.Client, name string) (*capability.Model, error) {
+	digest, err := m.digest(ctx, client, name)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	cached, ok := m.cache[digest]
+	m.lock.Unlock()
+	if ok {
+		model := *cached
+		model.Name = name
+		return &model, nil
+	}
+	resp, err := client.Show(ctx, &olm.ShowRequest{Model: name})
+	if err != nil {
+		return nil, err
+	}
+	model := capability.FromShow(name, digest, resp)
+	m.lock.Lock()
+	if m.cache == nil {
+		m.cache = make(map[string]*capability.Model)
+	}
+	m.cache[digest] = model
+	m.lock.Unlock()
+	copied := *model
+	return &copied, nil
+}
+
+// 获取会话使用的模型能力，无法获取或超时时不校验
+func (m *ModelCapabilities) session(session *SessionModel) *capability.Model {
+	ctx, cancel := context.WithTimeout(app.ctx, capabilityTimeout)
+	defer cancel()
+	model, err := m.get(ctx, profiles.apiClient(session.ProfileId), session.ModelName)
+	if err != nil {
+		log.Warn().Err(err).Str("model", session.ModelName).Msg("get model capabilities")
+		return nil
+	}
+	return model
+}
+
+// 校验会话设置，模型必须支持对话且上下文长度不超过模型支持的长度
+func (m *ModelCapabilities) validateSession(session *SessionModel) error {
+	model := m.session(session)
+	if model == nil {
+		return nil
+	}
+	if !model.Has(capability.ModelCompletion) {
+		return fmt.Errorf("model %s does not support chat", model.Name)
+	}
+	if session.Options == "" {
+		return nil
+	}
+	var options map[string]string
+	if err := json.Unmarshal([]byte(session.Options), &options); err != nil {
+		return err
+	}
+	if value := options["numCtx"]; value != "" {
+		numCtx, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid numCtx %q", value)
+		}
+		return model.ValidateNumCtx(numCtx)
+	}
+	return nil
+}
+
+// 发送对话请求前校验模型是否支持请求内容
+func (m *ModelCapabilities) validateChat(session *SessionModel, request *olm.ChatRequest) error {
+	if model := m.session(session); model != nil {
+		return model.ValidateChat(request)
+	}
+	return nil
+}
+
+// Capabilities 模型的能力、上下文长度、参数量及量化方式
+func (m *Models) Capabilities(model string) (*capability.Model, error) {
+	resp, err := modelCapabilities.get(app.ctx, ollama.newApiClient(), model)
+	if err != nil {
+		log.Error().Err(err).Str("model", model).Msg("get model capabilities error")
+	}
+	return resp, err
+}
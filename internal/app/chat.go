@@ -21,14 +21,14 @@ type Chat struct {
 func (c *Chat) scanSession(rows *sql.Rows) (*SessionModel, error) {
 	session := &SessionModel{}
 	if err := rows.Scan(&session.Id, &session.SessionName, &session.ModelName,
-		&session.MessageHistoryCount, &session.KeepAlive, &session.SystemMessage, &session.Options, &session.CreatedAt, &session.UpdatedAt); err != nil {
+		&session.MessageHistoryCount, &session.KeepAlive, &session.SystemMessage, &session.Options, &session.ProfileId, &session.CreatedAt, &session.UpdatedAt); err != nil {
 		return nil, err
 	}
 	return session, nil
 }
 
 func (c *Chat) Sessions() ([]*SessionModel, error) {
-	sqlStr := `select id, session_name, model_name, message_history_count, keep_alive, system_message, options, created_at, updated_at
+	sqlStr := `select id, session_name, model_name, message_history_count, keep_alive, system_message, options, ifnull(profile_id, ''), created_at, updated_at
             from t_session
             order by created_at desc`
 	rows, err := dao.db().QueryContext(app.ctx, sqlStr)
@@ -50,14 +50,17 @@ func (c *Chat) Sessions() ([]*SessionModel, error) {
 }
 
 func (c *Chat) CreateSession(session *SessionModel) (*SessionModel, error) {
+	if err := modelCapabilities.validateSession(session); err != nil {
+		return nil, err
+	}
 	session.Id = uuid.NewString()
 	session.CreatedAt = time.Now()
 	session.UpdatedAt = session.CreatedAt
 
-	sqlStr := `insert into t_session(id, session_name, model_name, message_history_count, keep_alive, system_message, options, created_at, updated_at)
-               values (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	sqlStr := `insert into t_session(id, session_name, model_name, message_history_count, keep_alive, system_message, options, profile_id, created_at, updated_at)
+               values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err := dao.db().ExecContext(app.ctx, sqlStr, session.Id, session.SessionName, session.ModelName,
-		session.MessageHistoryCount, session.KeepAlive, session.SystemMessage, session.Options, session.CreatedAt, session.UpdatedAt)
+		session.MessageHistoryCount, session.KeepAlive, session.SystemMessage, session.Options, session.ProfileId, session.CreatedAt, session.UpdatedAt)
 	return session, err
 }
 
@@ -80,17 +83,20 @@ func (c *Chat) DeleteSession(id string) (string, error) {
 }
 
 func (c *Chat) UpdateSession(session *SessionModel) (*SessionModel, error) {
+	if err := modelCapabilities.validateSession(session); err != nil {
+		return nil, err
+	}
 	session.UpdatedAt = session.CreatedAt
 
-	sqlStr := `update t_session set session_name = ?, model_name = ?, message_history_count = ?, keep_alive = ?, system_message = ?, options = ?, updated_at = ?
+	sqlStr := `update t_session set session_name = ?, model_name = ?, message_history_count = ?, keep_alive = ?, system_message = ?, options = ?, profile_id = ?, updated_at = ?
                where id = ?`
 	_, err := dao.db().ExecContext(app.ctx, sqlStr, session.SessionName, session.ModelName,
-		session.MessageHistoryCount, session.KeepAlive, session.SystemMessage, session.Options, session.UpdatedAt, session.Id)
+		session.MessageHistoryCount, session.KeepAlive, session.SystemMessage, session.Options, session.ProfileId, session.UpdatedAt, session.Id)
 	return session, err
 }
 
 func (c *Chat) GetSession(id string) (*SessionModel, error) {
-	sqlStr := `select id, session_name, model_name, message_history_count, keep_alive, system_message, options, created_at, updated_at
+	sqlStr := `select id, session_name, model_name, message_history_count, keep_alive, system_message, options, ifnull(profile_id, ''), created_at, updated_at
             from t_session
             where id = ?`
 	rows, err := dao.db().QueryContext(app.ctx, sqlStr, id)
@@ -384,8 +390,16 @@ func (c *Chat) chat(session *SessionModel, message *ChatMessageModel) {
 		Options:   options,
 	}
 	log.Debug().Any("request", request).Msg("chat request")
+	if err := modelCapabilities.validateChat(session, request); err != nil {
+		c.emitChatError(message, err)
+		return
+	}
+	if err := ollama.requireRequest(session.ProfileId, len(request.Tools), request.Format); err != nil {
+		c.emitChatError(message, err)
+		return
+	}
 
-	err = ollama.newApiClient().Chat(app.ctx, request, func(response olm.ChatResponse) error {
+	err = profiles.apiClient(session.ProfileId).Chat(app.ctx, request, func(response olm.ChatResponse) error {
 		respMessage := response.Message
 		buffer.WriteString(respMessage.Content)
 		fullContent := buffer.String()
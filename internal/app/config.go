@@ -1,9 +1,18 @@
 package app
 
 import (
+	"crypto/x509"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
 	"ollama-desktop/internal/config"
 	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/api"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,12 +20,30 @@ const (
 	configOllamaScheme = "ollama.scheme"
 	configOllamaHost   = "ollama.host"
 	configOllamaPort   = "ollama.port"
+	// 以下配置以 JSON 格式存储
+	configOllamaHeaders = "ollama.headers"
+	configOllamaAuth    = "ollama.auth"
+	configOllamaTLS     = "ollama.tls"
 
 	configProxyScheme   = "proxy.scheme"
 	configProxyHost     = "proxy.host"
 	configProxyPort     = "proxy.port"
 	configProxyUsername = "proxy.username"
 	configProxyPassword = "proxy.password"
+	configProxyMode     = "proxy.mode"
+	configProxyNoProxy  = "proxy.no_proxy"
+	configProxyPacUrl   = "proxy.pac_url"
+
+	// 访问外部服务时使用的 CA 证书文件
+	configTLSCaFile = "tls.ca_file"
+	// 不校验证书的主机，多个以逗号分隔
+	configTLSInsecureHosts = "tls.insecure_hosts"
+
+	// 显存大小，单位 MiB
+	configHardwareVram = "hardware.vram"
+
+	// 应用启动 Ollama 服务时使用的环境变量，以 JSON 格式存储
+	configOllamaEnvs = "ollama.envs"
 )
 
 var configStore = Config{}
@@ -94,9 +121,22 @@ func (c *Config) set(key, value string) error {
 }
 
 type OllamaConfig struct {
-	Scheme string `json:"scheme"`
-	Host   string `json:"host"`
-	Port   string `json:"port"`
+	Scheme  string            `json:"scheme"`
+	Host    string            `json:"host"`
+	Port    string            `json:"port"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Auth    api.Auth          `json:"auth"`
+	TLS     api.TLSOptions    `json:"tls"`
+}
+
+// 校验认证及证书配置
+func validateConnect(auth *api.Auth, options api.TLSOptions) error {
+	if err := auth.Validate(); err != nil {
+		return err
+	}
+	api.ResetHttpClients()
+	_, err := api.NewHttpClient(options)
+	return err
 }
 
 func (c *Config) OllamaConfigs() (*OllamaConfig, error) {
@@ -115,6 +155,7 @@ func (c *Config) OllamaConfigs() (*OllamaConfig, error) {
 		if value == "" {
 			continue
 		}
+		var err error
 		switch name {
 		case configOllamaScheme:
 			ollamaConfig.Scheme = value
@@ -122,12 +163,37 @@ func (c *Config) OllamaConfigs() (*OllamaConfig, error) {
 			ollamaConfig.Host = value
 		case configOllamaPort:
 			ollamaConfig.Port = value
+		case configOllamaHeaders:
+			err = json.Unmarshal([]byte(value), &ollamaConfig.Headers)
+		case configOllamaAuth:
+			err = json.Unmarshal([]byte(value), &ollamaConfig.Auth)
+		case configOllamaTLS:
+			err = json.Unmarshal([]byte(value), &ollamaConfig.TLS)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("key", name).Msg("parse ollama config error")
+			return nil, err
 		}
 	}
 	return ollamaConfig, nil
 }
 
 func (c *Config) SaveOllamaConfigs(request *OllamaConfig) error {
+	if err := validateConnect(&request.Auth, request.TLS); err != nil {
+		return err
+	}
+	headers, err := jsonValue(request.Headers, len(request.Headers) == 0)
+	if err != nil {
+		return err
+	}
+	auth, err := jsonValue(request.Auth, request.Auth.Type == "")
+	if err != nil {
+		return err
+	}
+	tlsOptions, err := jsonValue(request.TLS, request.TLS == api.TLSOptions{})
+	if err != nil {
+		return err
+	}
 	if err := c.set(configOllamaScheme, request.Scheme); err != nil {
 		return err
 	}
@@ -139,16 +205,50 @@ func (c *Config) SaveOllamaConfigs(request *OllamaConfig) error {
 		c.configs(true)
 		return err
 	}
+	if err := c.set(configOllamaHeaders, headers); err != nil {
+		c.configs(true)
+		return err
+	}
+	if err := c.set(configOllamaAuth, auth); err != nil {
+		c.configs(true)
+		return err
+	}
+	if err := c.set(configOllamaTLS, tlsOptions); err != nil {
+		c.configs(true)
+		return err
+	}
 	c.configs(true)
 	return nil
 }
 
+// 配置值序列化为 JSON，未设置时保存空字符串
+func jsonValue(value any, empty bool) (string, error) {
+	if empty {
+		return "", nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// 代理模式，为空时使用手动配置的代理
+const (
+	proxyModeManual = "manual"
+	proxyModeSystem = "system"
+	proxyModePac    = "pac"
+)
+
 type ProxyConfig struct {
+	Mode string `json:"mode"`
+	// http、https 或 socks5
 	Scheme   string `json:"scheme"`
 	Host     string `json:"host"`
 	Port     string `json:"port"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// 不使用代理的主机，格式同 NO_PROXY 环境变量，如 localhost,192.168.0.0/16,.lan
+	NoProxy string `json:"noProxy"`
+	// PAC 文件路径或地址
+	PacUrl string `json:"pacUrl"`
 }
 
 func (c *Config) ProxyConfigs() (*ProxyConfig, error) {
@@ -182,31 +282,202 @@ func (c *Config) ProxyConfigs() (*ProxyConfig, error) {
 			proxyConfig.Username = value
 		case configProxyPassword:
 			proxyConfig.Password = value
+		case configProxyMode:
+			proxyConfig.Mode = value
+		case configProxyNoProxy:
+			proxyConfig.NoProxy = value
+		case configProxyPacUrl:
+			proxyConfig.PacUrl = value
 		}
 	}
 	return proxyConfig, nil
 }
 
 func (c *Config) SaveProxyConfigs(request *ProxyConfig) error {
-	if err := c.set(configProxyScheme, request.Scheme); err != nil {
-		return err
+	switch request.Mode {
+	case "", proxyModeManual, proxyModeSystem:
+	case proxyModePac:
+		resetPac()
+		if _, err := loadPac(request.PacUrl); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported proxy mode %q", request.Mode)
 	}
-	if err := c.set(configProxyHost, request.Host); err != nil {
-		c.configs(true)
+	switch request.Scheme {
+	case "", "http", "https", "socks5":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", request.Scheme)
+	}
+	values := [][2]string{
+		{configProxyMode, request.Mode},
+		{configProxyScheme, request.Scheme},
+		{configProxyHost, request.Host},
+		{configProxyPort, request.Port},
+		{configProxyUsername, request.Username},
+		{configProxyPassword, request.Password},
+		{configProxyNoProxy, request.NoProxy},
+		{configProxyPacUrl, request.PacUrl},
+	}
+	for _, value := range values {
+		if err := c.set(value[0], value[1]); err != nil {
+			c.configs(true)
+			return err
+		}
+	}
+	c.configs(true)
+	return nil
+}
+
+type ProxyTestResult struct {
+	Url string `json:"url"`
+	// 实际使用的代理，直接连接时为 DIRECT
+	Proxy      string `json:"proxy"`
+	Success    bool   `json:"success"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	// 耗时，单位毫秒
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TestProxy 使用当前代理配置访问指定地址
+func (c *Config) TestProxy(target string) (*ProxyTestResult, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid url %q", target)
+	}
+	result := &ProxyTestResult{Url: target, Proxy: "DIRECT"}
+	proxy, err := proxyFunc()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	request, err := http.NewRequestWithContext(app.ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		if used, err := proxy(request); err != nil {
+			result.Error = err.Error()
+			return result, nil
+		} else if used != nil {
+			result.Proxy = used.Redacted()
+		}
+	}
+	start := time.Now()
+	resp, err := createHttpClient().Do(request)
+	result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	result.Success = resp.StatusCode < http.StatusInternalServerError
+	return result, nil
+}
+
+type HardwareConfig struct {
+	// 显存大小，单位 MiB，为空时视为无可用显卡
+	Vram string `json:"vram"`
+}
+
+func (c *Config) HardwareConfigs() (*HardwareConfig, error) {
+	vram, err := c.get(configHardwareVram)
+	if err != nil {
+		return nil, err
+	}
+	return &HardwareConfig{Vram: vram}, nil
+}
+
+func (c *Config) SaveHardwareConfigs(request *HardwareConfig) error {
+	if request.Vram != "" {
+		if _, err := strconv.ParseUint(request.Vram, 10, 64); err != nil {
+			return fmt.Errorf("invalid vram %q: %w", request.Vram, err)
+		}
+	}
+	err := c.set(configHardwareVram, request.Vram)
+	c.configs(true)
+	return err
+}
+
+type TLSConfig struct {
+	CaFile        string `json:"caFile"`
+	InsecureHosts string `json:"insecureHosts"`
+}
+
+func (t *TLSConfig) insecureHosts() map[string]bool {
+	hosts := make(map[string]bool)
+	for _, host := range strings.Split(t.InsecureHosts, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts[host] = true
+		}
+	}
+	return hosts
+}
+
+func (c *Config) TLSConfigs() (*TLSConfig, error) {
+	configs, err := c.configs(false)
+	if err != nil {
+		return nil, err
+	}
+	return &TLSConfig{
+		CaFile:        configs[configTLSCaFile],
+		InsecureHosts: configs[configTLSInsecureHosts],
+	}, nil
+}
+
+func (c *Config) SaveTLSConfigs(request *TLSConfig) error {
+	if request.CaFile != "" {
+		pem, err := os.ReadFile(request.CaFile)
+		if err != nil {
+			return err
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", request.CaFile)
+		}
+	}
+	if err := c.set(configTLSCaFile, request.CaFile); err != nil {
 		return err
 	}
-	if err := c.set(configProxyPort, request.Port); err != nil {
+	if err := c.set(configTLSInsecureHosts, request.InsecureHosts); err != nil {
 		c.configs(true)
 		return err
 	}
-	if err := c.set(configProxyUsername, request.Username); err != nil {
-		c.configs(true)
+	c.configs(true)
+	return nil
+}
+
+// 应用启动 Ollama 服务时使用的环境变量
+func (c *Config) ollamaEnvs() (map[string]string, error) {
+	envs := make(map[string]string)
+	value, err := c.get(configOllamaEnvs)
+	if err != nil || value == "" {
+		return envs, err
+	}
+	if err := json.Unmarshal([]byte(value), &envs); err != nil {
+		log.Error().Err(err).Msg("unmarshal ollama envs error")
+		return nil, err
+	}
+	return envs, nil
+}
+
+// 保存环境变量，值为空时删除
+func (c *Config) saveOllamaEnv(name, value string) error {
+	envs, err := c.ollamaEnvs()
+	if err != nil {
 		return err
 	}
-	if err := c.set(configProxyPassword, request.Password); err != nil {
-		c.configs(true)
+	if value == "" {
+		delete(envs, name)
+	} else {
+		envs[name] = value
+	}
+	data, err := json.Marshal(envs)
+	if err != nil {
 		return err
 	}
+	err = c.set(configOllamaEnvs, string(data))
 	c.configs(true)
-	return nil
+	return err
 }
@@ -33,6 +33,11 @@ func (d *Dao) shutdown() {
 	d.dao.Shutdown()
 }
 
+// 数据库是否已打开，应用启动前（如测试中）为 false
+func (d *Dao) ready() bool {
+	return d.dao != nil && d.dao.GetDb() != nil
+}
+
 func (d *Dao) db() *sql.DB {
 	return d.dao.GetDb()
 }
@@ -63,6 +68,7 @@ type SessionModel struct {
 	KeepAlive           string    `json:"keepAlive,omitempty"`
 	SystemMessage       string    `json:"systemMessage,omitempty"`
 	Options             string    `json:"options,omitempty"`
+	ProfileId           string    `json:"profileId,omitempty"`
 	CreatedAt           time.Time `json:"createdAt"`
 	UpdatedAt           time.Time `json:"updatedAt"`
 }
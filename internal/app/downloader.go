@@ -2,10 +2,16 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/mitchellh/go-homedir"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
 	ollama2 "ollama-desktop/internal/ollama"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,15 +27,34 @@ const (
 	pullEventSuccess  = "pull_success"
 	pullEventError    = "pull_error"
 	eventModelRefresh = "model_refresh"
+
+	taskActionPull = "pull"
+	taskActionPush = "push"
+	// 创建模型与拉取模型共用进度事件
+	taskActionCreate = "create"
+	// 导入本地模型文件
+	taskActionImport = "import"
+
+	defaultRegistryHost = "registry.ollama.ai"
+)
+
+var (
+	errModelBusy        = errors.New("another task is already running for this model")
+	errPushNamespace    = errors.New("pushing to ollama.com requires a namespaced model name, e.g. 'username/model:tag'")
+	errPushNoSigningKey = errors.New("no ollama signing key found, start the ollama server once to generate ~/.ollama/id_ed25519 and add the public key at https://ollama.com/settings/keys")
 )
 
 type DownloadItem struct {
-	Model    string `json:"model"`
+	Model string `json:"model"`
+	// 任务类型：pull、push
+	Action   string `json:"action"`
 	Insecure bool   `json:"insecure,omitempty"`
 	// 进度条数据
-	Bars     []*ProgressBar     `json:"bars"`
-	Canceled bool               `json:"-"`
-	cancel   context.CancelFunc `json:"-"`
+	Bars     []*ProgressBar `json:"bars"`
+	Error    string         `json:"error,omitempty"`
+	Canceled bool           `json:"-"`
+	ctx      context.Context
+	cancel   context.CancelFunc
 }
 
 type ProgressBar struct {
@@ -52,6 +77,9 @@ func (p *ProgressBar) set(percentage float64) {
 	}
 }
 
+// 任务执行函数，fn为进度回调
+type taskFunc func(ctx context.Context, fn func(ollama2.ProgressResponse) error) error
+
 type DownLoader struct {
 	tasks map[string]*DownloadItem
 	lock  sync.Mutex
@@ -61,31 +89,153 @@ func (d *DownLoader) Pull(request *ollama2.PullRequest) error {
 	if request.Model == "" && request.Name != "" {
 		request.Model = request.Name
 	}
+	item, err := d.addTask(taskActionPull, request.Model, request.Insecure)
+	if err != nil || item == nil {
+		return err
+	}
+	go d.run(item, func(ctx context.Context, fn func(ollama2.ProgressResponse) error) error {
+		return ollama.newApiClient().Pull(ctx, request, fn)
+	})
+	return nil
+}
+
+func (d *DownLoader) Push(request *ollama2.PushRequest) error {
+	if request.Model == "" && request.Name != "" {
+		request.Model = request.Name
+	}
+	if err := d.checkPush(request); err != nil {
+		log.Error().Err(err).Str("model", request.Model).Msg("push ollama model error")
+		return err
+	}
+	item, err := d.addTask(taskActionPush, request.Model, request.Insecure)
+	if err != nil || item == nil {
+		return err
+	}
+	go d.run(item, func(ctx context.Context, fn func(ollama2.ProgressResponse) error) error {
+		err := ollama.newApiClient().Push(ctx, request, fn)
+		if err != nil {
+			return pushError(err)
+		}
+		return nil
+	})
+	return nil
+}
+
+// 根据 Modelfile 创建模型
+func (d *DownLoader) create(request *ollama2.CreateRequest) error {
+	item, err := d.addTask(taskActionCreate, request.Model, false)
+	if err != nil || item == nil {
+		return err
+	}
+	go d.run(item, func(ctx context.Context, fn func(ollama2.ProgressResponse) error) error {
+		return ollama.newApiClient().Create(ctx, request, fn)
+	})
+	return nil
+}
+
+// 推送前检查模型名称及签名密钥
+func (d *DownLoader) checkPush(request *ollama2.PushRequest) error {
+	if request.Model == "" {
+		return errors.New("model name is required")
+	}
+	host, path := splitRegistryHost(request.Model)
+	if host != defaultRegistryHost {
+		// 私有仓库由仓库自身进行认证
+		return nil
+	}
+	if !strings.Contains(path, "/") || strings.HasPrefix(path, "library/") {
+		return errPushNamespace
+	}
+	// 仅当Ollama服务在本机时才能检查签名密钥
+	if !ollama.isLocalServer() {
+		return nil
+	}
+	for _, keyFile := range signingKeyFiles() {
+		if _, err := os.Stat(keyFile); err == nil {
+			return nil
+		}
+	}
+	return errPushNoSigningKey
+}
+
+// 拆分模型名称中的仓库地址
+func splitRegistryHost(model string) (string, string) {
+	parts := strings.SplitN(model, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1]
+	}
+	return defaultRegistryHost, model
+}
+
+// Ollama服务使用的签名密钥可能的位置
+func signingKeyFiles() []string {
+	var files []string
+	if home, err := homedir.Dir(); err == nil {
+		files = append(files, filepath.Join(home, ".ollama", "id_ed25519"))
+	}
+	// Linux 下以 systemd 服务方式安装时使用 ollama 用户
+	files = append(files, "/usr/share/ollama/.ollama/id_ed25519")
+	return files
+}
+
+// 转换推送错误，给出更明确的提示
+func pushError(err error) error {
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "unauthorized") || strings.Contains(message, "public key") {
+		return fmt.Errorf("push unauthorized, make sure the public key of the ollama server (~/.ollama/id_ed25519.pub) is added at https://ollama.com/settings/keys: %w", err)
+	}
+	return err
+}
+
+// 添加任务，同一模型同时只能存在一个任务
+func (d *DownLoader) addTask(action, model string, insecure bool) (*DownloadItem, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 	if d.tasks == nil {
 		d.tasks = make(map[string]*DownloadItem)
 	}
-	if _, ok := d.tasks[request.Model]; ok {
-		return nil
+	if item, ok := d.tasks[model]; ok {
+		if item.Action != action {
+			return nil, errModelBusy
+		}
+		return nil, nil
 	}
 	item := &DownloadItem{
-		Model:    request.Model,
-		Insecure: request.Insecure,
+		Model:    model,
+		Action:   action,
+		Insecure: insecure,
 		Bars:     nil,
 	}
-	d.tasks[request.Model] = item
-	go d.pull(request, item)
-	return nil
+	// 在锁内设置取消函数，Cancel 可能在任务开始执行前调用
+	item.ctx, item.cancel = context.WithCancel(app.ctx)
+	d.tasks[model] = item
+	return item, nil
 }
 
-func (d *DownLoader) pull(request *ollama2.PullRequest, item *DownloadItem) {
-	ctx, cancel := context.WithCancel(app.ctx)
-	item.cancel = cancel
+func (d *DownLoader) removeTask(model string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if item, ok := d.tasks[model]; ok {
+		// 释放任务的上下文
+		item.cancel()
+	}
+	delete(d.tasks, model)
+}
+
+func (d *DownLoader) canceled(item *DownloadItem) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return item.Canceled
+}
+
+func (d *DownLoader) run(item *DownloadItem, task taskFunc) {
+	ctx := item.ctx
 	d.emit(pullStatusWait, item)
 
 	cache := make(map[string]*ProgressBar)
 	var status string
 	var spinner *ProgressBar
-	err := ollama.newApiClient().Pull(ctx, request, func(resp ollama2.ProgressResponse) error {
+	err := task(ctx, func(resp ollama2.ProgressResponse) error {
 		if resp.Digest != "" {
 			if spinner != nil {
 				spinner.stop()
@@ -93,15 +243,19 @@ func (d *DownLoader) pull(request *ollama2.PullRequest, item *DownloadItem) {
 			bar, ok := cache[resp.Digest]
 			if !ok {
 				bar = &ProgressBar{
-					Name:       fmt.Sprintf("pulling %s", resp.Digest[7:19]),
+					Name:       resp.Status,
 					Percentage: 0,
 					Status:     "",
 				}
+				if bar.Name == "" {
+					bar.Name = fmt.Sprintf("%sing %s", item.Action, shortDigest(resp.Digest))
+				}
 				item.Bars = append(item.Bars, bar)
 				cache[resp.Digest] = bar
 			}
-			percentage := float64(resp.Completed) / float64(resp.Total) * 100
-			bar.set(percentage)
+			if resp.Total > 0 {
+				bar.set(float64(resp.Completed) / float64(resp.Total) * 100)
+			}
 		} else if status != resp.Status {
 			if spinner != nil {
 				spinner.stop()
@@ -121,8 +275,10 @@ func (d *DownLoader) pull(request *ollama2.PullRequest, item *DownloadItem) {
 	})
 
 	if err != nil {
-		delete(d.tasks, request.Model)
-		if !item.Canceled {
+		log.Error().Err(err).Str("action", item.Action).Str("model", item.Model).Msg("ollama model task error")
+		item.Error = err.Error()
+		d.removeTask(item.Model)
+		if !d.canceled(item) {
 			d.emit(pullStatusError, item)
 		} else {
 			d.emit(pullStatusPulling, item)
@@ -135,8 +291,8 @@ func (d *DownLoader) pull(request *ollama2.PullRequest, item *DownloadItem) {
 
 		<-time.After(2 * time.Second)
 
-		delete(d.tasks, request.Model)
-		if !item.Canceled {
+		d.removeTask(item.Model)
+		if !d.canceled(item) {
 			d.emit(pullStatusSuccess, item)
 		} else {
 			d.emit(pullStatusPulling, item)
@@ -144,9 +300,16 @@ func (d *DownLoader) pull(request *ollama2.PullRequest, item *DownloadItem) {
 	}
 }
 
+// 截取摘要用于展示，sha256:0123456789ab...
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
 func (d *DownLoader) emit(status int, item *DownloadItem) {
-	d.lock.Lock()
-	defer d.lock.Unlock()
 	runtime.EventsEmit(app.ctx, pullEventList, d.List())
 	switch status {
 	case pullStatusSuccess:
@@ -158,19 +321,18 @@ func (d *DownLoader) emit(status int, item *DownloadItem) {
 }
 
 func (d *DownLoader) Cancel(model string) {
-	if d.tasks == nil {
-		return
-	}
+	d.lock.Lock()
 	if item, ok := d.tasks[model]; ok {
-		if item.cancel != nil {
-			item.Canceled = true
-			item.cancel()
-		}
+		item.Canceled = true
+		item.cancel()
 	}
+	d.lock.Unlock()
 	runtime.EventsEmit(app.ctx, pullEventList, d.List())
 }
 
 func (d *DownLoader) List() []*DownloadItem {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 	if d.tasks == nil {
 		return nil
 	}
@@ -2,10 +2,20 @@ package app
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/net/http/httpproxy"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
-	"ollama-desktop/internal/config"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/pac"
+	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -25,34 +35,179 @@ func proxyUrl(scheme, host, port, username, password string) *url.URL {
 }
 
 func createHttpClient() *http.Client {
-	var proxy func(*http.Request) (*url.URL, error)
+	proxy, err := proxyFunc()
+	if err != nil {
+		log.Error().Err(err).Msg("create proxy error")
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second, // 设置超时时间为 30 秒
+		Transport: &certErrorTransport{
+			Transport: &http.Transport{
+				Proxy:           proxy,
+				TLSClientConfig: outboundTLSConfig(),
+			},
+		},
+	}
+}
 
-	var scheme, host, port, username, password string
-	if config.Config.Proxy != nil {
-		proxy := config.Config.Proxy
-		scheme = proxy.Scheme
-		host = proxy.Host
-		port = proxy.Port
-		username = proxy.Username
-		password = proxy.Password
+// 按代理配置生成代理函数，配置无效时不使用代理
+func proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxyConfig, err := configStore.ProxyConfigs()
+	if err != nil {
+		return nil, err
+	}
+	switch proxyConfig.Mode {
+	case proxyModeSystem:
+		return http.ProxyFromEnvironment, nil
+	case proxyModePac:
+		script, err := loadPac(proxyConfig.PacUrl)
+		if err != nil {
+			return nil, err
+		}
+		// 仅用于判断主机是否在排除列表中，代理地址为占位值
+		noProxy := (&httpproxy.Config{NoProxy: proxyConfig.NoProxy, HTTPProxy: "pac", HTTPSProxy: "pac"}).ProxyFunc()
+		return func(request *http.Request) (*url.URL, error) {
+			if u, _ := noProxy(request.URL); u == nil {
+				return nil, nil
+			}
+			proxy, err := script.Proxy(request.URL)
+			if err != nil {
+				// 与浏览器一致，PAC 执行失败时直接连接
+				log.Warn().Err(err).Str("url", request.URL.String()).Msg("evaluate pac error")
+				return nil, nil
+			}
+			return proxy, nil
+		}, nil
 	}
+	if proxyConfig.Scheme == "" || proxyConfig.Host == "" || proxyConfig.Port == "" {
+		return nil, nil
+	}
+	proxy := proxyUrl(proxyConfig.Scheme, proxyConfig.Host, proxyConfig.Port, proxyConfig.Username, proxyConfig.Password).String()
+	fn := (&httpproxy.Config{HTTPProxy: proxy, HTTPSProxy: proxy, NoProxy: proxyConfig.NoProxy}).ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return fn(request.URL)
+	}, nil
+}
 
-	scheme, _ = configStore.getOrDefault(configProxyScheme, scheme)
-	host, _ = configStore.getOrDefault(configProxyHost, host)
-	port, _ = configStore.getOrDefault(configProxyPort, port)
-	username, _ = configStore.getOrDefault(configProxyUsername, username)
-	password, _ = configStore.getOrDefault(configProxyPassword, password)
+var (
+	pacScripts = map[string]*pac.Script{}
+	pacLock    sync.Mutex
+)
 
-	if scheme != "" && host != "" && port != "" {
-		proxy = http.ProxyURL(proxyUrl(scheme, host, port, username, password))
+// 加载 PAC 文件，支持本地路径及 http(s) 地址，相同地址复用解析结果
+func loadPac(source string) (*pac.Script, error) {
+	pacLock.Lock()
+	defer pacLock.Unlock()
+	if script, ok := pacScripts[source]; ok {
+		return script, nil
 	}
-	return &http.Client{
-		Timeout: 30 * time.Second, // 设置超时时间为 30 秒
-		Transport: &http.Transport{
-			Proxy: proxy,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, // 不验证证书
+	var content []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		// PAC 文件本身直接下载，不经过代理，证书配置与其他外部请求一致
+		client := &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &certErrorTransport{
+				Transport: &http.Transport{TLSClientConfig: outboundTLSConfig()},
 			},
+		}
+		var resp *http.Response
+		if resp, err = client.Get(source); err == nil {
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("download pac file: %s", resp.Status)
+			}
+			content, err = io.ReadAll(resp.Body)
+		}
+	} else {
+		content, err = os.ReadFile(strings.TrimPrefix(source, "file://"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load pac file: %w", err)
+	}
+	script, err := pac.Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse pac file: %w", err)
+	}
+	pacScripts[source] = script
+	return script, nil
+}
+
+func resetPac() {
+	pacLock.Lock()
+	defer pacLock.Unlock()
+	pacScripts = map[string]*pac.Script{}
+}
+
+// 访问 ollama.com、GitHub、Gitee 等外部服务时的证书配置，默认校验证书
+func outboundTLSConfig() *tls.Config {
+	tlsConfig, _ := configStore.TLSConfigs()
+	if tlsConfig == nil {
+		tlsConfig = &TLSConfig{}
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil || roots == nil {
+		roots = x509.NewCertPool()
+	}
+	if tlsConfig.CaFile != "" {
+		if pem, err := os.ReadFile(tlsConfig.CaFile); err != nil {
+			log.Error().Err(err).Str("file", tlsConfig.CaFile).Msg("read ca file error")
+		} else if !roots.AppendCertsFromPEM(pem) {
+			log.Error().Str("file", tlsConfig.CaFile).Msg("no certificates found in ca file")
+		}
+	}
+	insecureHosts := tlsConfig.insecureHosts()
+	if len(insecureHosts) == 0 {
+		return &tls.Config{RootCAs: roots}
+	}
+	// 跳过内置校验，由 VerifyConnection 对未排除的主机按标准流程校验
+	return &tls.Config{
+		RootCAs:            roots,
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if insecureHosts[strings.ToLower(cs.ServerName)] {
+				return nil
+			}
+			opts := x509.VerifyOptions{
+				DNSName:       cs.ServerName,
+				Roots:         roots,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
 		},
 	}
 }
+
+const eventCertificateError = "certificate_error"
+
+// 证书校验失败时通知前端，并在错误中提示处理方式
+type certErrorTransport struct {
+	Transport http.RoundTripper
+}
+
+func (t *certErrorTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	resp, err := t.Transport.RoundTrip(request)
+	if err != nil && isCertificateError(err) {
+		host := request.URL.Hostname()
+		log.Error().Err(err).Str("host", host).Msg("verify certificate error")
+		runtime.EventsEmit(app.ctx, eventCertificateError, map[string]any{
+			"host":  host,
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("verify certificate of %s: %w (configure a CA file or skip verification for this host)", host, err)
+	}
+	return resp, err
+}
+
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var verification *tls.CertificateVerificationError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &invalid) ||
+		errors.As(err, &hostname) || errors.As(err, &verification)
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"io"
+	"ollama-desktop/internal/log"
+	olm "ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/modelfile"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 导入 safetensors 模型目录时需要打包的文件
+var safetensorsFilePatterns = []string{"*.safetensors", "*.json", "tokenizer.model"}
+
+type ImportModelRequest struct {
+	Model string `json:"model"`
+	// GGUF 文件、safetensors 文件或其所在目录
+	Path       string                `json:"path"`
+	System     string                `json:"system"`
+	Template   string                `json:"template"`
+	Parameters []modelfile.Parameter `json:"parameters"`
+	Quantize   string                `json:"quantize"`
+}
+
+// SelectModelFile 选择需要导入的模型文件
+func (o *Ollama) SelectModelFile() (string, error) {
+	return runtime.OpenFileDialog(app.ctx, runtime.OpenDialogOptions{
+		Title: "选择模型文件",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Model Files (*.gguf;*.safetensors)", Pattern: "*.gguf;*.safetensors"},
+		},
+	})
+}
+
+// Import 导入本地 GGUF 或 safetensors 模型，进度与拉取模型共用事件
+func (o *Ollama) Import(request *ImportModelRequest) error {
+	if request.Model == "" {
+		return errors.New("model name is required")
+	}
+	info, err := os.Stat(request.Path)
+	if err != nil {
+		log.Error().Err(err).Str("path", request.Path).Msg("import ollama model error")
+		return err
+	}
+	// safetensors 模型由目录中的多个文件组成
+	if !info.IsDir() && strings.EqualFold(filepath.Ext(request.Path), ".safetensors") {
+		request.Path = filepath.Dir(request.Path)
+	}
+	// 提前校验模板及参数，避免上传完成后才失败
+	if err := modelfile.Build("@sha256:", request.System, request.Template, request.Parameters).Validate(); err != nil {
+		return err
+	}
+	item, err := downloader.addTask(taskActionImport, request.Model, false)
+	if err != nil || item == nil {
+		return err
+	}
+	go downloader.run(item, func(ctx context.Context, fn func(olm.ProgressResponse) error) error {
+		return o.importModel(ctx, request, fn)
+	})
+	return nil
+}
+
+func (o *Ollama) importModel(ctx context.Context, request *ImportModelRequest, fn func(olm.ProgressResponse) error) error {
+	path := request.Path
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		fn(olm.ProgressResponse{Status: "packing safetensors files"})
+		zipFile, err := zipModelDir(ctx, path)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(zipFile)
+		path = zipFile
+	}
+
+	digest, size, err := fileDigest(ctx, path, fn)
+	if err != nil {
+		return err
+	}
+
+	client := o.newApiClient()
+	exists, err := client.HasBlob(ctx, digest)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		status := fmt.Sprintf("uploading %s", shortDigest(digest))
+		report := throttle(func(completed, total int64) {
+			fn(olm.ProgressResponse{Status: status, Digest: digest, Total: total, Completed: completed})
+		})
+		if err := client.UploadBlob(ctx, digest, file, size, report); err != nil {
+			return err
+		}
+	}
+
+	file := modelfile.Build("@"+digest, request.System, request.Template, request.Parameters)
+	return client.Create(ctx, &olm.CreateRequest{
+		Model:     request.Model,
+		Modelfile: file.String(),
+		Quantize:  request.Quantize,
+	}, fn)
+}
+
+// 计算文件的 sha256 摘要
+func fileDigest(ctx context.Context, path string, fn func(olm.ProgressResponse) error) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	status := fmt.Sprintf("hashing %s", filepath.Base(path))
+	report := throttle(func(completed, total int64) {
+		fn(olm.ProgressResponse{Status: status, Digest: "file:" + path, Total: total, Completed: completed})
+	})
+	hash := sha256.New()
+	writer := &progressWriter{ctx: ctx, total: info.Size(), fn: report}
+	if _, err := io.Copy(io.MultiWriter(hash, writer), file); err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), info.Size(), nil
+}
+
+// 将 safetensors 模型目录打包为 zip 文件
+func zipModelDir(ctx context.Context, dir string) (string, error) {
+	var files []string
+	for _, pattern := range safetensorsFilePatterns {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return "", err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no safetensors files found in %s", dir)
+	}
+
+	temp, err := os.CreateTemp("", "ollama-import-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer temp.Close()
+
+	writer := zip.NewWriter(temp)
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			os.Remove(temp.Name())
+			return "", err
+		}
+		if err := addZipFile(writer, name); err != nil {
+			os.Remove(temp.Name())
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		os.Remove(temp.Name())
+		return "", err
+	}
+	return temp.Name(), nil
+}
+
+func addZipFile(writer *zip.Writer, name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// 模型权重几乎无法压缩，直接存储
+	w, err := writer.CreateHeader(&zip.FileHeader{Name: filepath.Base(name), Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+type progressWriter struct {
+	ctx       context.Context
+	completed int64
+	total     int64
+	fn        func(completed, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	w.completed += int64(len(p))
+	w.fn(w.completed, w.total)
+	return len(p), nil
+}
+
+// 限制进度回调频率，避免过多的事件
+func throttle(fn func(completed, total int64)) func(completed, total int64) {
+	var last time.Time
+	return func(completed, total int64) {
+		if completed < total && time.Since(last) < 200*time.Millisecond {
+			return
+		}
+		last = time.Now()
+		fn(completed, total)
+	}
+}
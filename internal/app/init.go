@@ -73,6 +73,10 @@ func StartApp(server *assetserver.Options) error {
 			&ollama,
 			&chat,
 			&configStore,
+			&models,
+			&profiles,
+			&systemApp,
+			&serverLogs,
 		},
 		Logger:             &logger{},
 		LogLevelProduction: ll,
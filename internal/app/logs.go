@@ -0,0 +1,234 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/cmd"
+	"ollama-desktop/internal/ollama/serverlog"
+	"ollama-desktop/internal/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	gorun "runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// 应用启动的 ollama serve 进程输出
+	logSourceSupervisor = "supervisor"
+	// Ollama 写入的日志文件
+	logSourceFile = "file"
+	// systemd 服务日志
+	logSourceJournal = "journal"
+
+	eventServerLog = "server_log"
+
+	defaultLogLimit = 500
+	// 过滤时最多读取的原始行数
+	maxLogScan = 5000
+)
+
+var serverLogs = ServerLogs{}
+
+// ServerLogs Ollama 服务日志的读取及实时推送
+type ServerLogs struct {
+	cancel context.CancelFunc
+	lock   sync.Mutex
+}
+
+type ServerLogRequest struct {
+	// Source 日志来源，为空时自动选择
+	Source string `json:"source"`
+	serverlog.Filter
+	Limit int `json:"limit"`
+}
+
+type ServerLogResponse struct {
+	Source string            `json:"source"`
+	Path   string            `json:"path,omitempty"`
+	Lines  []*serverlog.Line `json:"lines"`
+}
+
+// Ollama 写入的日志文件路径
+func serverLogFile() string {
+	if gorun.GOOS == "windows" {
+		return filepath.Join(os.Getenv("LOCALAPPDATA"), "Ollama", "server.log")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ollama", "logs", "server.log")
+}
+
+// Sources 当前可用的日志来源
+func (s *ServerLogs) Sources() []string {
+	var sources []string
+	if serve.current() != nil {
+		sources = append(sources, logSourceSupervisor)
+	}
+	if path := serverLogFile(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			sources = append(sources, logSourceFile)
+		}
+	}
+	if gorun.GOOS == "linux" {
+		if _, err := exec.LookPath("journalctl"); err == nil {
+			sources = append(sources, logSourceJournal)
+		}
+	}
+	return sources
+}
+
+// 未指定来源时优先使用应用启动的进程输出
+func (s *ServerLogs) source(source string) (string, error) {
+	sources := s.Sources()
+	if len(sources) == 0 {
+		return "", errors.New("no ollama server log found")
+	}
+	if source == "" {
+		return sources[0], nil
+	}
+	for _, item := range sources {
+		if item == source {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("log source %s is not available", source)
+}
+
+// Tail 读取最近的日志
+func (s *ServerLogs) Tail(request *ServerLogRequest) (*ServerLogResponse, error) {
+	source, err := s.source(request.Source)
+	if err != nil {
+		return nil, err
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultLogLimit
+	}
+	scan := limit
+	if request.Level != "" || request.Query != "" {
+		scan = maxLogScan
+	}
+	resp := &ServerLogResponse{Source: source}
+	var raws []string
+	switch source {
+	case logSourceSupervisor:
+		raws = serve.current().Lines(scan)
+	case logSourceFile:
+		resp.Path = serverLogFile()
+		raws, err = serverlog.TailFile(resp.Path, scan)
+	case logSourceJournal:
+		var output []byte
+		ctx, cancel := context.WithTimeout(app.ctx, 10*time.Second)
+		defer cancel()
+		output, err = util.GetInvoker().CommandWithContext(ctx, "journalctl", "-u", cmd.SystemdUnit,
+			"--no-pager", "-o", "cat", "-n", fmt.Sprint(scan))
+		raws, _ = serverlog.ParseJournal(string(output))
+	}
+	if err != nil {
+		log.Error().Err(err).Str("source", source).Msg("read server log error")
+		return nil, err
+	}
+	resp.Lines = request.Apply(raws, limit)
+	return resp, nil
+}
+
+// Follow 实时推送新增的日志，同时只跟踪一个来源
+func (s *ServerLogs) Follow(request *ServerLogRequest) error {
+	source, err := s.source(request.Source)
+	if err != nil {
+		return err
+	}
+	read, err := s.follower(source)
+	if err != nil {
+		return err
+	}
+	s.Unfollow()
+	ctx, cancel := context.WithCancel(app.ctx)
+	s.lock.Lock()
+	s.cancel = cancel
+	s.lock.Unlock()
+	filter := request.Filter
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				raws, err := read()
+				if err != nil {
+					log.Warn().Err(err).Str("source", source).Msg("follow server log")
+					continue
+				}
+				if lines := filter.Apply(raws, 0); len(lines) > 0 {
+					runtime.EventsEmit(app.ctx, eventServerLog, source, lines)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Unfollow 停止推送日志
+func (s *ServerLogs) Unfollow() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// 返回读取新增日志的函数，从当前位置开始
+func (s *ServerLogs) follower(source string) (func() ([]string, error), error) {
+	switch source {
+	case logSourceSupervisor:
+		supervisor := serve.current()
+		_, seq := supervisor.LinesSince(0)
+		return func() ([]string, error) {
+			var lines []string
+			lines, seq = supervisor.LinesSince(seq)
+			return lines, nil
+		}, nil
+	case logSourceFile:
+		return serverlog.NewFileFollower(serverLogFile()).Read, nil
+	case logSourceJournal:
+		journal := func(args ...string) ([]string, string, error) {
+			ctx, cancel := context.WithTimeout(app.ctx, 10*time.Second)
+			defer cancel()
+			args = append([]string{"-u", cmd.SystemdUnit, "--no-pager", "-o", "cat", "--show-cursor"}, args...)
+			output, err := util.GetInvoker().CommandWithContext(ctx, "journalctl", args...)
+			if err != nil {
+				return nil, "", err
+			}
+			lines, cursor := serverlog.ParseJournal(string(output))
+			return lines, cursor, nil
+		}
+		// 读取最后一条日志以获取游标
+		since := time.Now().Format("2006-01-02 15:04:05")
+		_, cursor, err := journal("-n", "1")
+		if err != nil {
+			return nil, err
+		}
+		return func() ([]string, error) {
+			args := []string{"--since", since}
+			if cursor != "" {
+				args = []string{"--after-cursor", cursor}
+			}
+			lines, next, err := journal(args...)
+			if next != "" {
+				cursor = next
+			}
+			return lines, err
+		}, nil
+	}
+	return nil, fmt.Errorf("log source %s is not available", source)
+}
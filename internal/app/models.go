@@ -0,0 +1,239 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/mitchellh/go-homedir"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
+	olm "ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/fit"
+	"ollama-desktop/internal/ollama/registry"
+	"ollama-desktop/internal/ollama/storage"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var models = Models{}
+
+var errRemoteServer = errors.New("only available when the ollama server runs on this machine")
+
+type Models struct {
+}
+
+// DiskUsage 分析本地模型的磁盘占用，考虑模型之间共享的数据文件
+func (m *Models) DiskUsage() (*storage.Usage, error) {
+	if !ollama.isLocalServer() {
+		return nil, errRemoteServer
+	}
+	dir, err := m.modelsDir()
+	if err != nil {
+		return nil, err
+	}
+	usage, err := storage.Scan(dir)
+	if err != nil {
+		log.Error().Err(err).Str("dir", dir).Msg("scan ollama models error")
+	}
+	return usage, err
+}
+
+// 模型存储目录，优先使用服务的 OLLAMA_MODELS 环境变量
+func (m *Models) modelsDir() (string, error) {
+	if dir := ollama.envValue("OLLAMA_MODELS"); dir != "" {
+		return homedir.Expand(dir)
+	}
+	var dirs []string
+	if home, err := homedir.Dir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".ollama", "models"))
+	}
+	// Linux 下以 systemd 服务方式安装时使用 ollama 用户
+	dirs = append(dirs, "/usr/share/ollama/.ollama/models")
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", errors.New("could not locate the ollama models directory, set OLLAMA_MODELS")
+}
+
+// FitReport 模型各标签在本机硬件上的运行评估
+type FitReport struct {
+	Model  string        `json:"model"`
+	NumCtx int           `json:"numCtx"`
+	Vram   uint64        `json:"vram"`
+	Memory *fit.Memory   `json:"memory"`
+	Tags   []*fit.Result `json:"tags"`
+}
+
+// FitReport 根据标签大小、本机内存及配置的显存评估每个标签能否运行，
+// numCtx 为 0 时使用服务的 OLLAMA_CONTEXT_LENGTH，未设置时使用默认上下文长度
+func (m *Models) FitReport(model string, numCtx int) (*FitReport, error) {
+	if numCtx < 0 {
+		return nil, fmt.Errorf("invalid num_ctx %d", numCtx)
+	}
+	resp, err := ollama.ModelTagsOnline(model)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := fit.SystemMemory()
+	if err != nil {
+		log.Warn().Err(err).Msg("read system memory")
+		memory = &fit.Memory{}
+	}
+	var vram uint64
+	if value, _ := configStore.get(configHardwareVram); value != "" {
+		mib, _ := strconv.ParseUint(value, 10, 64)
+		vram = mib * 1024 * 1024
+	}
+	if numCtx == 0 {
+		numCtx = fit.DefaultNumCtx
+		if value, err := strconv.Atoi(ollama.envValue("OLLAMA_CONTEXT_LENGTH")); err == nil && value > 0 {
+			numCtx = value
+		}
+	}
+
+	report := &FitReport{
+		Model:  model,
+		NumCtx: numCtx,
+		Vram:   vram,
+		Memory: memory,
+	}
+	for _, tag := range resp.Tags {
+		size := tag.SizeBytes
+		if size == 0 {
+			// 页面上的大小形如 4.4GB
+			parsed, err := humanize.ParseBytes(tag.Size)
+			if err != nil {
+				log.Warn().Err(err).Str("tag", tag.Name).Msg("parse tag size")
+				continue
+			}
+			size = int64(parsed)
+		}
+		report.Tags = append(report.Tags, fit.Evaluate(fit.Tag{
+			Name:          tag.Name,
+			Size:          size,
+			ParameterSize: tag.ParameterSize,
+			Quantization:  tag.Quantization,
+		}, numCtx, vram, memory.Available))
+	}
+	return report, nil
+}
+
+const eventModelUpdates = "model_updates"
+
+type ModelUpdate struct {
+	Model        string    `json:"model"`
+	LocalDigest  string    `json:"localDigest"`
+	RemoteDigest string    `json:"remoteDigest"`
+	HasUpdate    bool      `json:"hasUpdate"`
+	Error        string    `json:"error,omitempty"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// Updates 查询最近一次的模型更新检查结果
+func (m *Models) Updates() ([]*ModelUpdate, error) {
+	sqlStr := `select model_name, local_digest, remote_digest, has_update, error_message, checked_at
+            from t_model_update
+            order by model_name`
+	rows, err := dao.db().QueryContext(app.ctx, sqlStr)
+	if err != nil {
+		log.Error().Err(err).Msg("query model update error")
+		return nil, err
+	}
+	defer rows.Close()
+	var updates []*ModelUpdate
+	for rows.Next() {
+		update := &ModelUpdate{}
+		if err := rows.Scan(&update.Model, &update.LocalDigest, &update.RemoteDigest, &update.HasUpdate,
+			&update.Error, &update.CheckedAt); err != nil {
+			log.Error().Err(err).Msg("fill model update error")
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+	return updates, nil
+}
+
+// CheckUpdates 对比本地模型与仓库中的清单摘要，检查模型是否存在更新
+func (m *Models) CheckUpdates() ([]*ModelUpdate, error) {
+	resp, err := ollama.newApiClient().List(app.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("list ollama model error")
+		return nil, err
+	}
+	client := &registry.Client{Http: createHttpClient()}
+	updates := make([]*ModelUpdate, len(resp.Models))
+	// 限制并发请求数量
+	sem := make(chan struct{}, 4)
+	var wg sync.WaitGroup
+	for i, model := range resp.Models {
+		wg.Add(1)
+		go func(i int, model olm.ListModelResponse) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			updates[i] = m.checkUpdate(client, model)
+		}(i, model)
+	}
+	wg.Wait()
+
+	if err := m.saveUpdates(updates); err != nil {
+		return nil, err
+	}
+	runtime.EventsEmit(app.ctx, eventModelUpdates, updates)
+	return updates, nil
+}
+
+func (m *Models) checkUpdate(client *registry.Client, model olm.ListModelResponse) *ModelUpdate {
+	update := &ModelUpdate{
+		Model:       model.Name,
+		LocalDigest: strings.TrimPrefix(model.Digest, "sha256:"),
+		CheckedAt:   time.Now(),
+	}
+	_, digest, err := client.Manifest(app.ctx, registry.ParseName(model.Name))
+	if err != nil {
+		log.Warn().Err(err).Str("model", model.Name).Msg("check model update")
+		update.Error = err.Error()
+		return update
+	}
+	update.RemoteDigest = strings.TrimPrefix(digest, "sha256:")
+	update.HasUpdate = update.RemoteDigest != update.LocalDigest
+	return update
+}
+
+// 保存检查结果，仅保留当前存在的模型
+func (m *Models) saveUpdates(updates []*ModelUpdate) error {
+	return dao.transaction(func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(app.ctx, `delete from t_model_update`); err != nil {
+			log.Error().Err(err).Msg("delete model update error")
+			return err
+		}
+		sqlStr := `insert into t_model_update(model_name, local_digest, remote_digest, has_update, error_message, checked_at)
+               values (?, ?, ?, ?, ?, ?)`
+		for _, update := range updates {
+			if _, err := tx.ExecContext(app.ctx, sqlStr, update.Model, update.LocalDigest, update.RemoteDigest,
+				update.HasUpdate, update.Error, update.CheckedAt); err != nil {
+				log.Error().Err(err).Msg("save model update error")
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Update 重新拉取模型以完成更新
+func (m *Models) Update(model string) error {
+	return downloader.Pull(&olm.PullRequest{Model: model})
+}
+
+func (m *Models) checkUpdatesJob() {
+	if _, err := m.CheckUpdates(); err != nil {
+		log.Warn().Err(err).Msg("check model updates")
+	}
+}
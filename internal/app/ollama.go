@@ -1,6 +1,9 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/hashicorp/go-version"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"net"
@@ -10,149 +13,450 @@ import (
 	"ollama-desktop/internal/log"
 	olm "ollama-desktop/internal/ollama"
 	"ollama-desktop/internal/ollama/api"
+	"ollama-desktop/internal/ollama/capability"
 	"ollama-desktop/internal/ollama/cmd"
+	"ollama-desktop/internal/ollama/envconfig"
+	"ollama-desktop/internal/ollama/modelfile"
 	ollama2 "ollama-desktop/internal/ollama/ollama"
+	"ollama-desktop/internal/ollama/registry"
 	"ollama-desktop/internal/util"
 	"os"
 	gorun "runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 var ollama = Ollama{}
 
 type Ollama struct {
-	started       bool
-	version       *version.Version
-	lastVersion   *util.Item
-	checkUpgraded bool
-	lock          sync.Mutex
+	connected            bool
+	version              *version.Version
+	versionLock          sync.RWMutex // 保护 connected 及 version，读取版本时不等待心跳检测
+	lastVersion          *util.Item
+	lastVersionCheckedAt time.Time
+	lock                 sync.Mutex // 保护 lastVersion 及 lastVersionCheckedAt
+	status               *OllamaStatus
+	statusLock           sync.Mutex
+	parserHealth         ParserHealth
+	degradedPages        map[string]bool // 解析不完整的页面类型，重新完整解析后移除
+	parserLock           sync.Mutex
+}
+
+const (
+	eventParserDegraded = "parser_degraded"
+	eventOllamaStatus   = "ollama_status"
+
+	// 服务不可访问时的检测间隔
+	pollDisconnected = 2 * time.Second
+	// 服务可访问时的检测间隔，状态不变时逐渐增加到最大值
+	pollMinInterval = 5 * time.Second
+	pollMaxInterval = 30 * time.Second
+	// 获取 Ollama 最新版本失败后的重试间隔
+	lastVersionRetryInterval = 10 * time.Minute
+	// 心跳检测中每个请求的超时时间
+	heartbeatTimeout = 5 * time.Second
+)
+
+// OllamaStatus Ollama 服务状态
+type OllamaStatus struct {
+	Reachable bool `json:"reachable"`
+	Installed bool `json:"installed"`
+	// CanStart 服务未启动且可由应用启动
+	CanStart    bool       `json:"canStart"`
+	Version     string     `json:"version"`
+	Upgrade     bool       `json:"upgrade"`
+	LastVersion *util.Item `json:"lastVersion,omitempty"`
+	// Latency 心跳请求耗时，单位毫秒
+	Latency       int64     `json:"latency"`
+	RunningModels []string  `json:"runningModels"`
+	LastError     string    `json:"lastError,omitempty"`
+	CheckedAt     time.Time `json:"checkedAt"`
+}
+
+// 比较状态是否相同，忽略每次检测都会变化的耗时及检测时间
+func (s *OllamaStatus) equal(other *OllamaStatus) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	lastVersion := func(status *OllamaStatus) string {
+		if status.LastVersion == nil {
+			return ""
+		}
+		return status.LastVersion.Name
+	}
+	return s.Reachable == other.Reachable && s.Installed == other.Installed && s.CanStart == other.CanStart &&
+		s.Version == other.Version && s.Upgrade == other.Upgrade && lastVersion(s) == lastVersion(other) &&
+		strings.Join(s.RunningModels, ",") == strings.Join(other.RunningModels, ",") && s.LastError == other.LastError
+}
+
+// ParserHealth ollama.com 页面解析状态
+type ParserHealth struct {
+	Healthy   bool                `json:"healthy"`
+	Degraded  int                 `json:"degraded"`
+	LastError *ollama2.ParseError `json:"lastError,omitempty"`
+	LastTime  time.Time           `json:"lastTime,omitempty"`
 }
 
 func (o *Ollama) Envs() []*OllamaEnvVar {
 	envs := []*OllamaEnvVar{
-		{"OLLAMA_DEBUG", cleanEnvValue("OLLAMA_DEBUG"), "Show additional debug information (e.g. OLLAMA_DEBUG=1)"},
-		{"OLLAMA_FLASH_ATTENTION", cleanEnvValue("OLLAMA_FLASH_ATTENTION"), "Enabled flash attention"},
-		{"OLLAMA_KV_CACHE_TYPE", cleanEnvValue("OLLAMA_KV_CACHE_TYPE"), "Quantization type for the K/V cache (default: f16)"},
-		{"OLLAMA_GPU_OVERHEAD", cleanEnvValue("OLLAMA_GPU_OVERHEAD"), "Reserve a portion of VRAM per GPU (bytes)"},
-		{"OLLAMA_HOST", cleanEnvValue("OLLAMA_HOST"), "IP Address for the ollama server (default 127.0.0.1:11434)"},
-		{"OLLAMA_KEEP_ALIVE", cleanEnvValue("OLLAMA_KEEP_ALIVE"), "The duration that models stay loaded in memory (default \"5m\")"},
-		{"OLLAMA_LLM_LIBRARY", cleanEnvValue("OLLAMA_LLM_LIBRARY"), "Set LLM library to bypass autodetection"},
-		{"OLLAMA_LOAD_TIMEOUT", cleanEnvValue("OLLAMA_LOAD_TIMEOUT"), "How long to allow model loads to stall before giving up (default \"5m\")"},
-		{"OLLAMA_MAX_LOADED_MODELS", cleanEnvValue("OLLAMA_MAX_LOADED_MODELS"), "Maximum number of loaded models per GPU"},
-		{"OLLAMA_MAX_QUEUE", cleanEnvValue("OLLAMA_MAX_QUEUE"), "Maximum number of queued requests"},
+		newEnvVar("OLLAMA_DEBUG", "Show additional debug information (e.g. OLLAMA_DEBUG=1)"),
+		newEnvVar("OLLAMA_FLASH_ATTENTION", "Enabled flash attention"),
+		newEnvVar("OLLAMA_KV_CACHE_TYPE", "Quantization type for the K/V cache (default: f16)"),
+		newEnvVar("OLLAMA_GPU_OVERHEAD", "Reserve a portion of VRAM per GPU (bytes)"),
+		newEnvVar("OLLAMA_HOST", "IP Address for the ollama server (default 127.0.0.1:11434)"),
+		newEnvVar("OLLAMA_KEEP_ALIVE", "The duration that models stay loaded in memory (default \"5m\")"),
+		newEnvVar("OLLAMA_LLM_LIBRARY", "Set LLM library to bypass autodetection"),
+		newEnvVar("OLLAMA_LOAD_TIMEOUT", "How long to allow model loads to stall before giving up (default \"5m\")"),
+		newEnvVar("OLLAMA_MAX_LOADED_MODELS", "Maximum number of loaded models per GPU"),
+		newEnvVar("OLLAMA_MAX_QUEUE", "Maximum number of queued requests"),
 		//{"OLLAMA_MAX_VRAM", cleanEnvValue(""), "Maximum VRAM"},
-		{"OLLAMA_MODELS", cleanEnvValue("OLLAMA_MODELS"), "The path to the models directory"},
-		{"OLLAMA_NOHISTORY", cleanEnvValue("OLLAMA_NOHISTORY"), "Do not preserve readline history"},
-		{"OLLAMA_NOPRUNE", cleanEnvValue("OLLAMA_NOPRUNE"), "Do not prune model blobs on startup"},
-		{"OLLAMA_NUM_PARALLEL", cleanEnvValue("OLLAMA_NUM_PARALLEL"), "Maximum number of parallel requests"},
-		{"OLLAMA_ORIGINS", cleanEnvValue("OLLAMA_ORIGINS"), "A comma separated list of allowed origins"},
+		newEnvVar("OLLAMA_MODELS", "The path to the models directory"),
+		newEnvVar("OLLAMA_NOHISTORY", "Do not preserve readline history"),
+		newEnvVar("OLLAMA_NOPRUNE", "Do not prune model blobs on startup"),
+		newEnvVar("OLLAMA_NUM_PARALLEL", "Maximum number of parallel requests"),
+		newEnvVar("OLLAMA_ORIGINS", "A comma separated list of allowed origins"),
 		//{"OLLAMA_RUNNERS_DIR", cleanEnvValue(""), "Location for runners"},
-		{"OLLAMA_SCHED_SPREAD", cleanEnvValue("OLLAMA_SCHED_SPREAD"), "Always schedule model across all GPUs"},
+		newEnvVar("OLLAMA_SCHED_SPREAD", "Always schedule model across all GPUs"),
 		//{"OLLAMA_TMPDIR", cleanEnvValue(""), "Location for temporary files"},
-		{"OLLAMA_MULTIUSER_CACHE", cleanEnvValue("OLLAMA_MULTIUSER_CACHE"), "Optimize prompt caching for multi-user scenarios"},
+		newEnvVar("OLLAMA_MULTIUSER_CACHE", "Optimize prompt caching for multi-user scenarios"),
 
 		// Informational
-		{"HTTP_PROXY", cleanEnvValue("HTTP_PROXY"), "HTTP proxy"},
-		{"HTTPS_PROXY", cleanEnvValue("HTTPS_PROXY"), "HTTPS proxy"},
-		{"NO_PROXY", cleanEnvValue("NO_PROXY"), "No proxy"},
+		newEnvVar("HTTP_PROXY", "HTTP proxy"),
+		newEnvVar("HTTPS_PROXY", "HTTPS proxy"),
+		newEnvVar("NO_PROXY", "No proxy"),
 	}
 	if gorun.GOOS != "windows" {
 		// Windows environment variables are case-insensitive so there's no need to duplicate them
-		envs = append(envs, &OllamaEnvVar{"http_proxy", cleanEnvValue("http_proxy"), "HTTP proxy"})
-		envs = append(envs, &OllamaEnvVar{"https_proxy", cleanEnvValue("https_proxy"), "HTTPS proxy"})
-		envs = append(envs, &OllamaEnvVar{"no_proxy", cleanEnvValue("no_proxy"), "No proxy"})
+		envs = append(envs, newEnvVar("http_proxy", "HTTP proxy"))
+		envs = append(envs, newEnvVar("https_proxy", "HTTPS proxy"))
+		envs = append(envs, newEnvVar("no_proxy", "No proxy"))
 	}
 	if gorun.GOOS != "darwin" {
-		envs = append(envs, &OllamaEnvVar{"CUDA_VISIBLE_DEVICES", cleanEnvValue("CUDA_VISIBLE_DEVICES"), "Set which NVIDIA devices are visible"})
-		envs = append(envs, &OllamaEnvVar{"HIP_VISIBLE_DEVICES", cleanEnvValue("HIP_VISIBLE_DEVICES"), "Set which AMD devices are visible"})
-		envs = append(envs, &OllamaEnvVar{"ROCR_VISIBLE_DEVICES", cleanEnvValue("ROCR_VISIBLE_DEVICES"), "Set which AMD devices are visible"})
-		envs = append(envs, &OllamaEnvVar{"GPU_DEVICE_ORDINAL", cleanEnvValue("GPU_DEVICE_ORDINAL"), "Set which AMD devices are visible"})
-		envs = append(envs, &OllamaEnvVar{"HSA_OVERRIDE_GFX_VERSION", cleanEnvValue("HSA_OVERRIDE_GFX_VERSION"), "Override the gfx used for all detected AMD GPUs"})
-		envs = append(envs, &OllamaEnvVar{"OLLAMA_INTEL_GPU", cleanEnvValue("OLLAMA_INTEL_GPU"), "Enable experimental Intel GPU detection"})
+		envs = append(envs, newEnvVar("CUDA_VISIBLE_DEVICES", "Set which NVIDIA devices are visible"))
+		envs = append(envs, newEnvVar("HIP_VISIBLE_DEVICES", "Set which AMD devices are visible"))
+		envs = append(envs, newEnvVar("ROCR_VISIBLE_DEVICES", "Set which AMD devices are visible"))
+		envs = append(envs, newEnvVar("GPU_DEVICE_ORDINAL", "Set which AMD devices are visible"))
+		envs = append(envs, newEnvVar("HSA_OVERRIDE_GFX_VERSION", "Override the gfx used for all detected AMD GPUs"))
+		envs = append(envs, newEnvVar("OLLAMA_INTEL_GPU", "Enable experimental Intel GPU detection"))
+	}
+	// 数据库未打开时只返回进程环境变量
+	var configured map[string]string
+	if dao.ready() {
+		var err error
+		if configured, err = configStore.ollamaEnvs(); err != nil {
+			log.Error().Err(err).Msg("get ollama envs error")
+		}
+	}
+	applied, running := serve.appliedEnvs()
+	for _, env := range envs {
+		env.Configured = configured[env.Name]
+		if running {
+			env.Applied = applied[env.Name]
+			env.Pending = env.Configured != env.Applied
+		}
 	}
 	return envs
 }
 
+// SaveEnv 保存应用启动服务时使用的环境变量，值为空时删除，需重启服务后生效
+func (o *Ollama) SaveEnv(name, value string) error {
+	found := false
+	for _, env := range o.Envs() {
+		if env.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("unsupported environment variable %s", name)
+	}
+	value, err := envconfig.Validate(name, value)
+	if err != nil {
+		return err
+	}
+	if err := configStore.saveOllamaEnv(name, value); err != nil {
+		log.Error().Err(err).Str("name", name).Msg("save ollama env error")
+		return err
+	}
+	return nil
+}
+
+func newEnvVar(name, description string) *OllamaEnvVar {
+	return &OllamaEnvVar{Name: name, Value: cleanEnvValue(name), Description: description}
+}
+
+// 服务使用的环境变量值，依次为应用启动的服务使用的值、应用中保存的值及进程环境变量
+func (e *OllamaEnvVar) effective() string {
+	if e.Applied != "" {
+		return e.Applied
+	}
+	if e.Configured != "" {
+		return e.Configured
+	}
+	return e.Value
+}
+
+// 服务使用的环境变量值，不支持的环境变量返回进程环境变量
+func (o *Ollama) envValue(name string) string {
+	for _, env := range o.Envs() {
+		if env.Name == name {
+			return env.effective()
+		}
+	}
+	return cleanEnvValue(name)
+}
+
 // Clean quotes and spaces from the value
 func cleanEnvValue(key string) string {
-	return strings.Trim(strings.TrimSpace(os.Getenv(key)), "\"'")
+	return envconfig.Clean(os.Getenv(key))
 }
 
 type OllamaEnvVar struct {
 	Name        string
 	Value       string
 	Description string
+	// Configured 应用中保存的值，启动服务时使用
+	Configured string
+	// Applied 应用启动的服务当前使用的值
+	Applied string
+	// Pending 保存的值与运行中服务的值不一致，需重启服务
+	Pending bool
+}
+
+// Installation 本机的安装信息，包括可执行文件版本及 systemd 服务状态
+func (o *Ollama) Installation() (*cmd.Installation, error) {
+	installation, err := cmd.DetectInstallation(app.ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("detect ollama installation error")
+		return nil, err
+	}
+	return installation, nil
 }
 
 func (o *Ollama) Version() (string, error) {
 	return o.newApiClient().Version(app.ctx)
 }
 
+// Heartbeat 立即检测服务状态，状态变化时发送事件
 func (o *Ollama) Heartbeat() {
-	o.lock.Lock()
-	defer o.lock.Unlock()
-	var installed, started bool
+	o.heartbeat()
+}
+
+// Status 最近一次检测的服务状态，尚未检测时立即检测
+func (o *Ollama) Status() *OllamaStatus {
+	o.statusLock.Lock()
+	status := o.status
+	o.statusLock.Unlock()
+	if status == nil {
+		status, _ = o.heartbeat()
+	}
+	copied := *status
+	return &copied
+}
+
+// 当前服务版本，尚未获取时为空
+func (o *Ollama) serverVersion() *version.Version {
+	o.versionLock.RLock()
+	defer o.versionLock.RUnlock()
+	return o.version
+}
+
+// 清除服务版本，下次心跳检测时重新获取
+func (o *Ollama) resetVersion() {
+	o.versionLock.Lock()
+	o.version = nil
+	o.versionLock.Unlock()
+}
+
+// Capabilities 当前服务版本下各功能是否可用，版本未知时均视为可用
+func (o *Ollama) Capabilities() []*capability.Status {
+	return capability.List(o.serverVersion())
+}
+
+// 服务版本不支持功能时返回错误，避免发送请求后得到难以理解的错误
+func (o *Ollama) require(name string) error {
+	if err := capability.Check(name, o.serverVersion()); err != nil {
+		log.Warn().Err(err).Str("capability", name).Msg("ollama capability unavailable")
+		return err
+	}
+	return nil
+}
+
+// 请求使用了工具或 JSON Schema 格式时检查服务版本，profileId 不是当前服务时版本未知，不做检查
+func (o *Ollama) requireRequest(profileId string, tools int, format string) error {
+	if profileId != "" {
+		if current := profiles.current(); current == nil || current.Id != profileId {
+			return nil
+		}
+	}
+	if err := capability.CheckRequest(tools, format, o.serverVersion()); err != nil {
+		log.Warn().Err(err).Msg("ollama capability unavailable")
+		return err
+	}
+	return nil
+}
+
+// 最近一次检测时服务是否可访问，不触发检测
+func (o *Ollama) reachable() bool {
+	o.statusLock.Lock()
+	defer o.statusLock.Unlock()
+	return o.status != nil && o.status.Reachable
+}
+
+// 检测服务状态，返回最新状态及是否发生变化，网络请求期间不持有锁
+func (o *Ollama) heartbeat() (*OllamaStatus, bool) {
+	status := &OllamaStatus{CheckedAt: time.Now()}
 	client := o.newApiClient()
-	started = client.Heartbeat(app.ctx) == nil
-	if started != o.started {
-		o.started = started
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(app.ctx, heartbeatTimeout)
+	err := client.Heartbeat(ctx)
+	cancel()
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		status.Reachable = true
+		status.Latency = time.Since(start).Milliseconds()
+	}
+	o.versionLock.Lock()
+	if status.Reachable != o.connected {
+		o.connected = status.Reachable
 		o.version = nil
-		o.checkUpgraded = false
 	}
+	serverVersion := o.version
+	o.versionLock.Unlock()
 
-	if !started {
-		installed, _ = cmd.CheckInstalled(app.ctx)
+	if !status.Reachable {
+		ctx, cancel := context.WithTimeout(app.ctx, heartbeatTimeout)
+		status.Installed, _ = cmd.CheckInstalled(ctx)
+		cancel()
 	} else {
-		installed = true
+		status.Installed = true
 	}
-	if started && o.version == nil {
-		current, _ := client.Version(app.ctx)
+	if status.Reachable && serverVersion == nil {
+		ctx, cancel := context.WithTimeout(app.ctx, heartbeatTimeout)
+		current, _ := client.Version(ctx)
+		cancel()
 		if current != "" {
 			ver, err := version.NewVersion(strings.ToLower(current))
 			if err != nil {
 				log.Warn().Err(err).Msg("get ollama version")
 			} else {
+				serverVersion = ver
+				o.versionLock.Lock()
 				o.version = ver
+				o.versionLock.Unlock()
 			}
 		}
 	}
-	if o.lastVersion == nil {
-		client := createHttpClient()
-		release := util.GithubRelease{Http: client}
-		item, err := release.Last("ollama", "ollama")
+	if status.Reachable && capability.Supported(capability.Ps, serverVersion) {
+		ctx, cancel := context.WithTimeout(app.ctx, heartbeatTimeout)
+		running, err := client.ListRunning(ctx)
+		cancel()
 		if err != nil {
-			log.Warn().Err(err).Str("channel", release.Channel()).Msg("check ollama upgrade")
-		} else if item != nil {
-			log.Info().Str("channel", release.Channel()).Str("name", item.Name).Msg("check ollama upgrade")
-			o.lastVersion = item
+			log.Warn().Err(err).Msg("list running models")
+		} else {
+			for _, model := range running.Models {
+				status.RunningModels = append(status.RunningModels, model.Name)
+			}
+			sort.Strings(status.RunningModels)
 		}
 	}
-	upgrade := !o.checkUpgraded && o.lastVersion != nil && o.version != nil
-	if upgrade {
-		last, err := version.NewVersion(strings.ToLower(o.lastVersion.Name))
-		if err != nil {
-			log.Warn().Err(err).Msg("check ollama upgrade")
-		} else {
-			if last != nil {
-				upgrade = last.GreaterThan(o.version)
+	status.LastVersion = o.latestRelease()
+	if serverVersion != nil {
+		status.Version = serverVersion.String()
+		if status.LastVersion != nil {
+			last, err := version.NewVersion(strings.ToLower(status.LastVersion.Name))
+			if err != nil {
+				log.Warn().Err(err).Msg("check ollama upgrade")
+			} else {
+				status.Upgrade = last.GreaterThan(serverVersion)
 			}
-			o.checkUpgraded = true
 		}
-
 	}
 	goos := gorun.GOOS
-	current := ""
-	if o.version != nil {
-		current = o.version.String()
+	canStart := goos == "windows" || goos == "darwin" || goos == "linux" && o.isLocalServer()
+	status.CanStart = !status.Reachable && status.Installed && canStart
+
+	// 在锁内比较并发送，保证并发检测时事件顺序与保存的状态一致
+	o.statusLock.Lock()
+	defer o.statusLock.Unlock()
+	changed := !status.equal(o.status)
+	o.status = status
+	if changed {
+		runtime.EventsEmit(app.ctx, eventOllamaStatus, status)
+	}
+	return status, changed
+}
+
+// Ollama 最新发布，获取失败时间隔一段时间后重试，避免断开连接时频繁请求
+func (o *Ollama) latestRelease() *util.Item {
+	o.lock.Lock()
+	if o.lastVersion != nil || time.Since(o.lastVersionCheckedAt) <= lastVersionRetryInterval {
+		defer o.lock.Unlock()
+		return o.lastVersion
+	}
+	// 先记录检测时间，其他检测在请求期间不会重复获取
+	o.lastVersionCheckedAt = time.Now()
+	o.lock.Unlock()
+
+	release := util.GithubRelease{Http: createHttpClient()}
+	item, err := release.Last("ollama", "ollama")
+	if err != nil {
+		log.Warn().Err(err).Str("channel", release.Channel()).Msg("check ollama upgrade")
+		return nil
 	}
-	runtime.EventsEmit(app.ctx, "ollamaHeartbeat",
-		installed, started, !started && installed && (goos == "windows" || goos == "darwin"),
-		current, upgrade, o.lastVersion)
+	if item == nil {
+		return nil
+	}
+	log.Info().Str("channel", release.Channel()).Str("name", item.Name).Msg("check ollama upgrade")
+	o.lock.Lock()
+	o.lastVersion = item
+	o.lock.Unlock()
+	return item
+}
+
+// 按服务状态调整检测间隔，断开时快速重试，状态稳定后逐渐放慢
+func (o *Ollama) monitor(ctx context.Context) {
+	interval := pollDisconnected
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		status, changed := o.heartbeat()
+		switch {
+		case !status.Reachable:
+			interval = pollDisconnected
+		case changed:
+			interval = pollMinInterval
+		default:
+			interval *= 2
+			if interval > pollMaxInterval {
+				interval = pollMaxInterval
+			}
+		}
+	}
+}
+
+// 切换服务后重新检测服务状态
+func (o *Ollama) resetServer() {
+	o.versionLock.Lock()
+	o.connected = false
+	o.version = nil
+	o.versionLock.Unlock()
+	o.Heartbeat()
 }
 
 func (o *Ollama) Start() error {
-	err := cmd.StartApp(app.ctx, o.newApiClient())
+	var err error
+	if gorun.GOOS == "linux" {
+		err = serve.start()
+	} else {
+		err = cmd.StartApp(app.ctx, o.newApiClient())
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("start ollama app error")
 		return err
@@ -161,6 +465,20 @@ func (o *Ollama) Start() error {
 	return nil
 }
 
+// RestartServe 重启由应用启动的服务，使保存的环境变量生效
+func (o *Ollama) RestartServe() error {
+	if _, running := serve.appliedEnvs(); !running {
+		return errors.New("ollama server was not started by the app")
+	}
+	serve.stop()
+	return o.Start()
+}
+
+// ServeStatus 由应用启动的 ollama serve 进程状态
+func (o *Ollama) ServeStatus() cmd.SupervisorStatus {
+	return serve.status()
+}
+
 func (o *Ollama) List() (*olm.ListResponse, error) {
 	resp, err := o.newApiClient().List(app.ctx)
 	if err != nil {
@@ -170,6 +488,9 @@ func (o *Ollama) List() (*olm.ListResponse, error) {
 }
 
 func (o *Ollama) ListRunning() (*olm.ProcessResponse, error) {
+	if err := o.require(capability.Ps); err != nil {
+		return nil, err
+	}
 	resp, err := o.newApiClient().ListRunning(app.ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("list ollama running model error")
@@ -207,6 +528,57 @@ func (o *Ollama) Pull(requestId string, request *olm.PullRequest) error {
 	return nil
 }
 
+type CreateModelRequest struct {
+	Model string `json:"model"`
+	// Modelfile 内容，为空时根据以下字段生成
+	Modelfile  string                `json:"modelfile"`
+	From       string                `json:"from"`
+	System     string                `json:"system"`
+	Template   string                `json:"template"`
+	Parameters []modelfile.Parameter `json:"parameters"`
+	Quantize   string                `json:"quantize"`
+}
+
+func (r *CreateModelRequest) modelfile() (*modelfile.File, error) {
+	if strings.TrimSpace(r.Modelfile) == "" {
+		return modelfile.Build(r.From, r.System, r.Template, r.Parameters), nil
+	}
+	return modelfile.Parse(strings.NewReader(r.Modelfile))
+}
+
+func (o *Ollama) Create(request *CreateModelRequest) error {
+	if request.Model == "" {
+		return errors.New("model name is required")
+	}
+	if request.Quantize != "" {
+		if err := o.require(capability.Quantize); err != nil {
+			return err
+		}
+	}
+	file, err := request.modelfile()
+	if err == nil {
+		err = file.Validate()
+	}
+	if err != nil {
+		log.Error().Err(err).Str("model", request.Model).Msg("invalid modelfile")
+		return err
+	}
+	return downloader.create(&olm.CreateRequest{
+		Model:     request.Model,
+		Modelfile: file.String(),
+		Quantize:  request.Quantize,
+	})
+}
+
+// ValidateModelfile 在本地校验 Modelfile，错误信息中包含行号
+func (o *Ollama) ValidateModelfile(content string) error {
+	file, err := modelfile.Parse(strings.NewReader(content))
+	if err != nil {
+		return err
+	}
+	return file.Validate()
+}
+
 func (o *Ollama) Embeddings(request *olm.EmbeddingRequest) (*olm.EmbeddingResponse, error) {
 	log.Error().Any("request", request).Msg("Embeddings")
 	resp, err := o.newApiClient().Embeddings(app.ctx, request)
@@ -216,7 +588,19 @@ func (o *Ollama) Embeddings(request *olm.EmbeddingRequest) (*olm.EmbeddingRespon
 	return resp, err
 }
 
-func (o *Ollama) SearchOnline(request *olm.SearchRequest) ([]*olm.ModelInfo, error) {
+// Embed 批量生成向量，需要服务支持 /api/embed
+func (o *Ollama) Embed(request *olm.EmbedRequest) (*olm.EmbedResponse, error) {
+	if err := o.require(capability.Embed); err != nil {
+		return nil, err
+	}
+	resp, err := o.newApiClient().Embed(app.ctx, request)
+	if err != nil {
+		log.Error().Err(err).Msg("embed error")
+	}
+	return resp, err
+}
+
+func (o *Ollama) SearchOnline(request *olm.SearchRequest) (*olm.SearchResponse, error) {
 	resp, err := o.newOllamaClient().Search(app.ctx, request)
 	if err != nil {
 		log.Error().Err(err).Msg("search ollama model error")
@@ -224,7 +608,7 @@ func (o *Ollama) SearchOnline(request *olm.SearchRequest) ([]*olm.ModelInfo, err
 	return resp, err
 }
 
-func (o *Ollama) LibraryOnline(request *olm.LibraryRequest) ([]*olm.ModelInfo, error) {
+func (o *Ollama) LibraryOnline(request *olm.LibraryRequest) (*olm.SearchResponse, error) {
 	resp, err := o.newOllamaClient().Library(app.ctx, request)
 	if err != nil {
 		log.Error().Err(err).Msg("ollama library error")
@@ -249,26 +633,98 @@ func (o *Ollama) ModelTagsOnline(model string) (*olm.ModelTagsResponse, error) {
 }
 
 func (o *Ollama) newApiClient() *api.Client {
-	ollamaHost := config.Config.Ollama.Host
-
-	scheme, _ := configStore.getOrDefault(configOllamaScheme, ollamaHost.Scheme)
-	host, _ := configStore.getOrDefault(configOllamaHost, ollamaHost.Host)
-	port, _ := configStore.getOrDefault(configOllamaPort, ollamaHost.Port)
+	if profile := profiles.current(); profile != nil {
+		return profile.apiClient()
+	}
+	ollamaConfig, err := configStore.OllamaConfigs()
+	if err != nil {
+		ollamaHost := config.Config.Ollama.Host
+		ollamaConfig = &OllamaConfig{Scheme: ollamaHost.Scheme, Host: ollamaHost.Host, Port: ollamaHost.Port}
+	}
+	return connectClient(ollamaConfig.Scheme, ollamaConfig.Host, ollamaConfig.Port, ollamaConfig.Headers, ollamaConfig.Auth, ollamaConfig.TLS)
+}
 
-	return &api.Client{
+// 根据连接配置创建客户端，证书配置无效时仍返回客户端，由请求暴露错误
+func connectClient(scheme, host, port string, headers map[string]string, auth api.Auth, options api.TLSOptions) *api.Client {
+	client := &api.Client{
 		Base: &url.URL{
 			Scheme: scheme,
 			Host:   net.JoinHostPort(host, port),
 		},
 		Http: http.DefaultClient,
 	}
+	if len(headers) > 0 {
+		client.Headers = make(http.Header)
+		for name, value := range headers {
+			client.Headers.Set(name, value)
+		}
+	}
+	if auth.Type != "" {
+		client.Auth = &auth
+	}
+	if httpClient, err := api.NewHttpClient(options); err != nil {
+		log.Error().Err(err).Str("host", host).Msg("create ollama http client error")
+	} else {
+		client.Http = httpClient
+	}
+	return client
+}
+
+// 判断当前连接的Ollama服务是否在本机
+func (o *Ollama) isLocalServer() bool {
+	return isLoopbackHost(o.newApiClient().Base.Hostname())
+}
+
+// 判断主机是否为本机地址
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
 }
 
 func (o *Ollama) newOllamaClient() *ollama2.Client {
 	base, _ := url.Parse("https://ollama.com")
 
+	client := createHttpClient()
 	return &ollama2.Client{
-		Base: base,
-		Http: createHttpClient(),
+		Base:       base,
+		Http:       client,
+		Registry:   &registry.Client{Http: client},
+		Cache:      &onlineCache,
+		OnStale:    onlineCache.onStale,
+		OnDegraded: o.onParserDegraded,
+		OnParsed:   o.onParsed,
+	}
+}
+
+// 页面解析不完整时记录并通知前端，通常意味着 ollama.com 页面结构发生了变化
+func (o *Ollama) onParserDegraded(err *ollama2.ParseError) {
+	log.Warn().Err(err).Msg("parse ollama.com page degraded")
+	o.parserLock.Lock()
+	o.parserHealth.Degraded++
+	o.parserHealth.LastError = err
+	o.parserHealth.LastTime = time.Now()
+	if o.degradedPages == nil {
+		o.degradedPages = map[string]bool{}
 	}
+	o.degradedPages[err.Page] = true
+	o.parserLock.Unlock()
+	runtime.EventsEmit(app.ctx, eventParserDegraded, err)
+}
+
+// 页面完整解析后恢复该类页面的解析状态
+func (o *Ollama) onParsed(page string) {
+	o.parserLock.Lock()
+	defer o.parserLock.Unlock()
+	delete(o.degradedPages, page)
+}
+
+func (o *Ollama) ParserHealth() ParserHealth {
+	o.parserLock.Lock()
+	defer o.parserLock.Unlock()
+	health := o.parserHealth
+	health.Healthy = len(o.degradedPages) == 0
+	return health
 }
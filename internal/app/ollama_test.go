@@ -10,6 +10,7 @@ import (
 	olm "ollama-desktop/internal/ollama"
 	"ollama-desktop/internal/ollama/api"
 	ollama2 "ollama-desktop/internal/ollama/ollama"
+	"os"
 	"testing"
 )
 
@@ -20,11 +21,29 @@ func TestOllama_Envs(t *testing.T) {
 	}
 }
 
+func TestOllamaEnvVar_Effective(t *testing.T) {
+	testCases := []struct {
+		env      OllamaEnvVar
+		expected string
+	}{
+		{OllamaEnvVar{Value: "/process"}, "/process"},
+		{OllamaEnvVar{Value: "/process", Configured: "/configured"}, "/configured"},
+		{OllamaEnvVar{Value: "/process", Configured: "/configured", Applied: "/applied"}, "/applied"},
+	}
+	for _, tc := range testCases {
+		if actual := tc.env.effective(); actual != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, actual)
+		}
+	}
+}
+
 func TestOllama_Version(t *testing.T) {
+	requireOnline(t)
 	t.Log(newApiClient().Version(context.Background()))
 }
 
 func TestOllama_Show(t *testing.T) {
+	requireOnline(t)
 	resp, err := newApiClient().Show(context.Background(), &olm.ShowRequest{
 		Model: "qwen2:0.5b",
 	})
@@ -44,6 +63,7 @@ func TestOllama_Show(t *testing.T) {
 }
 
 func TestOllama_Chat(t *testing.T) {
+	requireOnline(t)
 	stream := true
 	err := newApiClient().Chat(context.Background(), &olm.ChatRequest{
 		Model: "llama3.1",
@@ -90,6 +110,7 @@ func TestOllama_Chat(t *testing.T) {
 }
 
 func TestOllama_ModelInfoOnline(t *testing.T) {
+	requireOnline(t)
 	resp, err := newOllamaClient().ModelInfo(context.Background(), "SIGJNF/deepseek-r1-671b-1.58bit")
 	if err != nil {
 		t.Fatal(err)
@@ -98,6 +119,7 @@ func TestOllama_ModelInfoOnline(t *testing.T) {
 }
 
 func TestOllama_ModelTags(t *testing.T) {
+	requireOnline(t)
 	resp, err := newOllamaClient().ModelTags(context.Background(), "SIGJNF/deepseek-r1-671b-1.58bit")
 	if err != nil {
 		t.Fatal(err)
@@ -106,6 +128,7 @@ func TestOllama_ModelTags(t *testing.T) {
 }
 
 func TestOllama_LibraryOnline(t *testing.T) {
+	requireOnline(t)
 	request := &olm.LibraryRequest{
 		Q:    "",
 		Sort: "",
@@ -114,26 +137,34 @@ func TestOllama_LibraryOnline(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	for _, item := range resp {
+	for _, item := range resp.Models {
 		pretty(t, item)
 	}
 }
 
 func TestOllama_SearchOnline(t *testing.T) {
+	requireOnline(t)
 	request := &olm.SearchRequest{
 		Q: "ddd",
 		O: "",
-		C: "tools",
+		C: []string{"tools"},
 	}
 	resp, err := newOllamaClient().Search(context.Background(), request)
 	if err != nil {
 		t.Fatal(err)
 	}
-	for _, item := range resp {
+	for _, item := range resp.Models {
 		pretty(t, item)
 	}
 }
 
+// 需要本机 Ollama 服务或访问 ollama.com 的测试，设置 OLLAMA_ONLINE_TEST=1 时执行
+func requireOnline(t *testing.T) {
+	if os.Getenv("OLLAMA_ONLINE_TEST") == "" {
+		t.Skip("set OLLAMA_ONLINE_TEST=1 to run against ollama and ollama.com")
+	}
+}
+
 func pretty(t *testing.T, value interface{}) {
 	data, _ := json.Marshal(value)
 	t.Log(string(data))
@@ -0,0 +1,318 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/api"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 当前使用的服务配置编号，为空时使用 ollama.scheme/host/port 配置
+	configServerProfile = "server.profile"
+
+	eventProfileStatus = "profile_status"
+
+	// 检测单个服务的超时时间，各服务并发检测，总耗时不超过该值
+	profileProbeTimeout = 5 * time.Second
+)
+
+var errProfileNotExists = errors.New("server profile not exists")
+
+var profiles = Profiles{}
+
+// Profiles 多个 Ollama 服务的连接配置
+type Profiles struct {
+	profileCaches []*ServerProfile
+	statuses      map[string]*ProfileStatus
+	lock          sync.Mutex
+}
+
+type ServerProfile struct {
+	Id          string            `json:"id"`
+	ProfileName string            `json:"profileName"`
+	Scheme      string            `json:"scheme"`
+	Host        string            `json:"host"`
+	Port        string            `json:"port"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Auth        api.Auth          `json:"auth"`
+	TLS         api.TLSOptions    `json:"tls"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// 以 JSON 格式存储在 options 字段中的扩展选项
+type profileOptions struct {
+	Auth api.Auth       `json:"auth"`
+	TLS  api.TLSOptions `json:"tls"`
+}
+
+type ProfileStatus struct {
+	Id        string    `json:"id"`
+	Online    bool      `json:"online"`
+	Version   string    `json:"version,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+func (p *Profiles) scanProfile(rows *sql.Rows) (*ServerProfile, error) {
+	profile := &ServerProfile{}
+	var headers, options string
+	if err := rows.Scan(&profile.Id, &profile.ProfileName, &profile.Scheme, &profile.Host, &profile.Port,
+		&headers, &options, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if headers != "" {
+		if err := json.Unmarshal([]byte(headers), &profile.Headers); err != nil {
+			return nil, err
+		}
+	}
+	if options != "" {
+		var value profileOptions
+		if err := json.Unmarshal([]byte(options), &value); err != nil {
+			return nil, err
+		}
+		profile.Auth = value.Auth
+		profile.TLS = value.TLS
+	}
+	return profile, nil
+}
+
+func (p *Profiles) profiles(forceUpdate bool) ([]*ServerProfile, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.profileCaches != nil && !forceUpdate {
+		return p.profileCaches, nil
+	}
+	sqlStr := `select id, profile_name, scheme, host, port, ifnull(headers, ''), ifnull(options, ''), created_at, updated_at
+            from t_server_profile
+            order by created_at`
+	rows, err := dao.db().QueryContext(app.ctx, sqlStr)
+	if err != nil {
+		log.Error().Err(err).Msg("query server profile error")
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]*ServerProfile, 0)
+	for rows.Next() {
+		profile, err := p.scanProfile(rows)
+		if err != nil {
+			log.Error().Err(err).Msg("fill server profile error")
+			return nil, err
+		}
+		list = append(list, profile)
+	}
+	p.profileCaches = list
+	return p.profileCaches, nil
+}
+
+func (p *Profiles) get(id string) *ServerProfile {
+	list, _ := p.profiles(false)
+	for _, profile := range list {
+		if profile.Id == id {
+			return profile
+		}
+	}
+	return nil
+}
+
+// 当前使用的服务配置，未选择时返回 nil
+func (p *Profiles) current() *ServerProfile {
+	id, _ := configStore.get(configServerProfile)
+	if id == "" {
+		return nil
+	}
+	return p.get(id)
+}
+
+// 指定服务配置的客户端，配置为空或不存在时使用当前服务
+func (p *Profiles) apiClient(id string) *api.Client {
+	if id != "" {
+		if profile := p.get(id); profile != nil {
+			return profile.apiClient()
+		}
+		log.Warn().Str("profile", id).Msg("server profile not exists, use current server")
+	}
+	return ollama.newApiClient()
+}
+
+func (s *ServerProfile) apiClient() *api.Client {
+	return connectClient(s.Scheme, s.Host, s.Port, s.Headers, s.Auth, s.TLS)
+}
+
+func (s *ServerProfile) validate() error {
+	s.ProfileName = strings.TrimSpace(s.ProfileName)
+	s.Host = strings.TrimSpace(s.Host)
+	s.Port = strings.TrimSpace(s.Port)
+	if s.ProfileName == "" {
+		return errors.New("profile name is required")
+	}
+	if s.Scheme != "http" && s.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if s.Host == "" || s.Port == "" {
+		return errors.New("host and port are required")
+	}
+	return validateConnect(&s.Auth, s.TLS)
+}
+
+func (s *ServerProfile) values() (headers string, options string, err error) {
+	if headers, err = jsonValue(s.Headers, len(s.Headers) == 0); err != nil {
+		return
+	}
+	options, err = jsonValue(&profileOptions{Auth: s.Auth, TLS: s.TLS}, s.Auth.Type == "" && s.TLS == api.TLSOptions{})
+	return
+}
+
+func (p *Profiles) List() ([]*ServerProfile, error) {
+	return p.profiles(false)
+}
+
+func (p *Profiles) Create(profile *ServerProfile) (*ServerProfile, error) {
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+	headers, options, err := profile.values()
+	if err != nil {
+		return nil, err
+	}
+	profile.Id = uuid.NewString()
+	profile.CreatedAt = time.Now()
+	profile.UpdatedAt = profile.CreatedAt
+
+	sqlStr := `insert into t_server_profile(id, profile_name, scheme, host, port, headers, options, created_at, updated_at)
+               values (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = dao.db().ExecContext(app.ctx, sqlStr, profile.Id, profile.ProfileName, profile.Scheme, profile.Host, profile.Port,
+		headers, options, profile.CreatedAt, profile.UpdatedAt)
+	if err != nil {
+		log.Error().Err(err).Msg("create server profile error")
+		return nil, err
+	}
+	p.profiles(true)
+	return profile, nil
+}
+
+func (p *Profiles) Update(profile *ServerProfile) (*ServerProfile, error) {
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+	headers, options, err := profile.values()
+	if err != nil {
+		return nil, err
+	}
+	profile.UpdatedAt = time.Now()
+
+	sqlStr := `update t_server_profile set profile_name = ?, scheme = ?, host = ?, port = ?, headers = ?, options = ?, updated_at = ?
+               where id = ?`
+	_, err = dao.db().ExecContext(app.ctx, sqlStr, profile.ProfileName, profile.Scheme, profile.Host, profile.Port,
+		headers, options, profile.UpdatedAt, profile.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("update server profile error")
+		return nil, err
+	}
+	p.profiles(true)
+	if current := p.current(); current != nil && current.Id == profile.Id {
+		go ollama.resetServer()
+	}
+	return profile, nil
+}
+
+func (p *Profiles) Delete(id string) (string, error) {
+	err := dao.transaction(func(tx *sql.Tx) error {
+		sqlStr := "delete from t_server_profile where id = ?"
+		if _, err := tx.ExecContext(app.ctx, sqlStr, id); err != nil {
+			log.Error().Err(err).Msg("delete server profile error")
+			return err
+		}
+		// 绑定该服务的会话改为使用当前服务
+		sqlStr = "update t_session set profile_id = null where profile_id = ?"
+		if _, err := tx.ExecContext(app.ctx, sqlStr, id); err != nil {
+			log.Error().Err(err).Msg("unbind session server profile error")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return id, err
+	}
+	p.profiles(true)
+	if current, _ := configStore.get(configServerProfile); current == id {
+		err = p.Use("")
+	}
+	return id, err
+}
+
+// Current 当前使用的服务配置，使用默认服务时返回 nil
+func (p *Profiles) Current() *ServerProfile {
+	return p.current()
+}
+
+// Use 切换当前使用的服务配置，id 为空时使用默认服务
+func (p *Profiles) Use(id string) error {
+	if id != "" && p.get(id) == nil {
+		return errProfileNotExists
+	}
+	err := configStore.set(configServerProfile, id)
+	configStore.configs(true)
+	if err != nil {
+		return err
+	}
+	go ollama.resetServer()
+	return nil
+}
+
+// Statuses 最近一次检测的各服务状态
+func (p *Profiles) Statuses() []*ProfileStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	list := make([]*ProfileStatus, 0, len(p.statuses))
+	for _, profile := range p.profileCaches {
+		if status, ok := p.statuses[profile.Id]; ok {
+			list = append(list, status)
+		}
+	}
+	return list
+}
+
+// 检测所有服务的状态并通知前端
+func (p *Profiles) heartbeat() {
+	list, err := p.profiles(false)
+	if err != nil || len(list) == 0 {
+		return
+	}
+	statuses := make(map[string]*ProfileStatus, len(list))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, profile := range list {
+		wg.Add(1)
+		go func(profile *ServerProfile) {
+			defer wg.Done()
+			status := &ProfileStatus{Id: profile.Id}
+			ctx, cancel := context.WithTimeout(app.ctx, profileProbeTimeout)
+			defer cancel()
+			if version, err := profile.apiClient().Version(ctx); err != nil {
+				status.Error = err.Error()
+			} else {
+				status.Online = true
+				status.Version = version
+			}
+			status.CheckedAt = time.Now()
+			lock.Lock()
+			statuses[profile.Id] = status
+			lock.Unlock()
+		}(profile)
+	}
+	wg.Wait()
+
+	p.lock.Lock()
+	p.statuses = statuses
+	p.lock.Unlock()
+	runtime.EventsEmit(app.ctx, eventProfileStatus, p.Statuses())
+}
@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
+	olm "ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/capability"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	eventRunningModels = "running_models"
+
+	// 保留最近一小时的采样，每 5 秒采样一次
+	maxRunningSamples = 720
+)
+
+var runningModels = RunningModels{}
+
+// RunningModels 定时采样已加载到内存中的模型
+type RunningModels struct {
+	samples []*RunningSample
+	lock    sync.Mutex
+}
+
+// RunningSample 某一时刻已加载的模型及占用的内存
+type RunningSample struct {
+	Time     time.Time                  `json:"time"`
+	Models   []olm.ProcessModelResponse `json:"models"`
+	Size     int64                      `json:"size"`
+	SizeVRAM int64                      `json:"sizeVram"`
+}
+
+// 模型列表、内存占用或过期时间变化时视为不同
+func (s *RunningSample) key() string {
+	var sb strings.Builder
+	for _, model := range s.Models {
+		fmt.Fprintf(&sb, "%s|%d|%d|%d;", model.Name, model.Size, model.SizeVRAM, model.ExpiresAt.Unix())
+	}
+	return sb.String()
+}
+
+func (r *RunningModels) sampleJob() {
+	if !ollama.reachable() || !capability.Supported(capability.Ps, ollama.serverVersion()) {
+		return
+	}
+	if _, err := r.sample(); err != nil {
+		log.Warn().Err(err).Msg("sample running models")
+	}
+}
+
+// 采样并记录历史，有变化时发送事件
+func (r *RunningModels) sample() (*RunningSample, error) {
+	resp, err := ollama.newApiClient().ListRunning(app.ctx)
+	if err != nil {
+		return nil, err
+	}
+	sample := &RunningSample{Time: time.Now(), Models: resp.Models}
+	for _, model := range resp.Models {
+		sample.Size += model.Size
+		sample.SizeVRAM += model.SizeVRAM
+	}
+	r.lock.Lock()
+	changed := len(r.samples) == 0 || r.samples[len(r.samples)-1].key() != sample.key()
+	r.samples = append(r.samples, sample)
+	if len(r.samples) > maxRunningSamples {
+		r.samples = r.samples[len(r.samples)-maxRunningSamples:]
+	}
+	r.lock.Unlock()
+	if changed {
+		runtime.EventsEmit(app.ctx, eventRunningModels, sample)
+	}
+	return sample, nil
+}
+
+func (r *RunningModels) history(since time.Time) []*RunningSample {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	samples := make([]*RunningSample, 0, len(r.samples))
+	for _, sample := range r.samples {
+		if sample.Time.After(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// 解析保留时长，支持 5m 等时长格式或整数秒，负数表示一直保留
+func parseKeepAlive(keepAlive string) (*olm.Duration, error) {
+	keepAlive = strings.TrimSpace(keepAlive)
+	if keepAlive == "" {
+		return nil, nil
+	}
+	if seconds, err := strconv.ParseInt(keepAlive, 10, 64); err == nil {
+		return &olm.Duration{Duration: time.Duration(seconds) * time.Second}, nil
+	}
+	duration, err := time.ParseDuration(keepAlive)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keep alive %q", keepAlive)
+	}
+	return &olm.Duration{Duration: duration}, nil
+}
+
+// 发送不含提示词的生成请求，仅加载或卸载模型
+func (r *RunningModels) keepAlive(model string, keepAlive *olm.Duration) error {
+	stream := false
+	request := &olm.GenerateRequest{Model: model, Stream: &stream, KeepAlive: keepAlive}
+	err := ollama.newApiClient().Generate(app.ctx, request, func(olm.GenerateResponse) error { return nil })
+	if err != nil {
+		return err
+	}
+	if _, err := r.sample(); err != nil {
+		log.Warn().Err(err).Msg("sample running models")
+	}
+	go ollama.Heartbeat()
+	return nil
+}
+
+// RunningHistory 最近的运行模型采样，minutes 小于等于 0 时返回全部
+func (o *Ollama) RunningHistory(minutes int) []*RunningSample {
+	since := time.Time{}
+	if minutes > 0 {
+		since = time.Now().Add(-time.Duration(minutes) * time.Minute)
+	}
+	return runningModels.history(since)
+}
+
+// Unload 立即从内存中卸载模型
+func (o *Ollama) Unload(model string) error {
+	if err := o.require(capability.KeepAlive); err != nil {
+		return err
+	}
+	if err := runningModels.keepAlive(model, &olm.Duration{}); err != nil {
+		log.Error().Err(err).Str("model", model).Msg("unload model error")
+		return err
+	}
+	return nil
+}
+
+// Preload 预先加载模型，keepAlive 为空时使用服务默认的保留时长
+func (o *Ollama) Preload(model string, keepAlive string) error {
+	duration, err := parseKeepAlive(keepAlive)
+	if err != nil {
+		return err
+	}
+	if duration != nil {
+		if err := o.require(capability.KeepAlive); err != nil {
+			return err
+		}
+	}
+	if err := runningModels.keepAlive(model, duration); err != nil {
+		log.Error().Err(err).Str("model", model).Msg("preload model error")
+		return err
+	}
+	return nil
+}
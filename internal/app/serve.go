@@ -0,0 +1,170 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"net"
+	"ollama-desktop/internal/config"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/cmd"
+	"ollama-desktop/internal/ollama/envconfig"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const eventServeState = "ollama_serve_state"
+
+var serve = ollamaServe{}
+
+// ollamaServe 由应用启动并监管的 ollama serve 进程，目前仅用于 Linux
+type ollamaServe struct {
+	supervisor *cmd.Supervisor
+	// 启动服务时使用的应用配置环境变量
+	applied map[string]string
+	lock    sync.Mutex
+}
+
+func (s *ollamaServe) start() error {
+	client := ollama.newApiClient()
+	if !isLoopbackHost(client.Base.Hostname()) {
+		return errors.New("only local ollama server can be started")
+	}
+	installation, err := cmd.DetectInstallation(app.ctx)
+	if err != nil {
+		return err
+	}
+	if !installation.Installed || installation.Path == "" {
+		return errors.New("ollama is not installed")
+	}
+	envs, err := configStore.ollamaEnvs()
+	if err != nil {
+		return err
+	}
+	env, err := serveEnv(envs, client.Base.Host)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	if s.supervisor == nil {
+		s.supervisor = cmd.NewSupervisor(installation.Path, client)
+		s.supervisor.Log = &lumberjack.Logger{
+			Filename:   config.ServeLogName,
+			MaxSize:    config.Config.Logging.MaxSize,
+			MaxBackups: config.Config.Logging.MaxBackups,
+			MaxAge:     config.Config.Logging.MaxAge,
+			Compress:   config.Config.Logging.Compress,
+			LocalTime:  config.Config.Logging.LocalTime,
+		}
+		s.supervisor.OnStateChange = s.onStateChange
+	}
+	supervisor := s.supervisor
+	if supervisor.Running() {
+		s.lock.Unlock()
+		return nil
+	}
+	// 端口被其他服务占用时，就绪检测会连接到该服务而误认为启动成功
+	address := envconfig.ListenAddress(client.Base.Host)
+	if configured, ok := envs["OLLAMA_HOST"]; ok {
+		address = envconfig.ListenAddress(configured)
+	}
+	if err := checkPortFree(address); err != nil {
+		s.lock.Unlock()
+		return err
+	}
+	supervisor.Path = installation.Path
+	supervisor.Client = client
+	supervisor.Env = env
+	s.applied = envs
+	s.lock.Unlock()
+	ctx, cancel := context.WithTimeout(app.ctx, time.Minute)
+	defer cancel()
+	return supervisor.Start(ctx)
+}
+
+// 尝试监听地址，判断端口是否已被其他进程占用
+func checkPortFree(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("%s is already in use, stop the other ollama server or change OLLAMA_HOST: %w", address, err)
+	}
+	return listener.Close()
+}
+
+// 在当前进程环境变量的基础上覆盖应用中配置的值，未配置 OLLAMA_HOST 时使用连接配置的地址
+func serveEnv(envs map[string]string, host string) ([]string, error) {
+	if configured, ok := envs["OLLAMA_HOST"]; ok {
+		_, port, _ := net.SplitHostPort(host)
+		if envconfig.Port(configured) != port {
+			return nil, fmt.Errorf("OLLAMA_HOST %s does not match the configured ollama server %s", configured, host)
+		}
+	} else {
+		merged := map[string]string{"OLLAMA_HOST": host}
+		for name, value := range envs {
+			merged[name] = value
+		}
+		envs = merged
+	}
+	env := make([]string, 0, len(envs))
+	for _, item := range os.Environ() {
+		name, _, _ := strings.Cut(item, "=")
+		if _, ok := envs[name]; !ok {
+			env = append(env, item)
+		}
+	}
+	for name, value := range envs {
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// 应用启动的服务使用的环境变量，running 表示服务由应用启动且正在运行
+func (s *ollamaServe) appliedEnvs() (map[string]string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.supervisor == nil || !s.supervisor.Running() {
+		return nil, false
+	}
+	return s.applied, true
+}
+
+// 停止由应用启动的服务，未启动时忽略
+func (s *ollamaServe) stop() {
+	s.lock.Lock()
+	supervisor := s.supervisor
+	s.lock.Unlock()
+	if supervisor != nil {
+		supervisor.Stop()
+	}
+}
+
+// 应用启动过服务时返回监管进程，用于读取输出
+func (s *ollamaServe) current() *cmd.Supervisor {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.supervisor
+}
+
+func (s *ollamaServe) status() cmd.SupervisorStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.supervisor == nil {
+		return cmd.SupervisorStatus{State: cmd.StateStopped}
+	}
+	return s.supervisor.Status()
+}
+
+func (s *ollamaServe) onStateChange(state string, err error) {
+	if err != nil {
+		log.Error().Err(err).Str("state", state).Msg("ollama serve error")
+	}
+	runtime.EventsEmit(app.ctx, eventServeState, s.status())
+	// 进程退出或恢复后及时刷新服务状态
+	if state == cmd.StateRunning || state == cmd.StateRestarting {
+		go ollama.Heartbeat()
+	}
+}
@@ -1,14 +1,36 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/cmd"
+	"ollama-desktop/internal/ollama/envconfig"
+	"ollama-desktop/internal/util"
 	"os"
+	"os/exec"
+	"regexp"
 	"runtime" // For runtime.GOOS
-	// Add other necessary imports
+	"strings"
+	"time"
 )
 
+var systemApp = SystemApp{}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 type SystemApp struct{}
 
+// EnvVarChange 修改系统服务环境变量的预览
+type EnvVarChange struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Diff    string `json:"diff"`
+	Changed bool   `json:"changed"`
+}
+
 // SetOllamaEnvVar sets a system environment variable.
 // This function needs to handle administrator privileges.
 func (s *SystemApp) SetOllamaEnvVar(name string, value string) error {
@@ -30,11 +52,7 @@ func (s *SystemApp) SetOllamaEnvVar(name string, value string) error {
 			// err = os.Setenv(name, value) // This is not system-wide
 			err = fmt.Errorf("macOS implementation pending for system-wide env var setting")
 		case "linux":
-			// TODO: Implement Linux-specific logic for admin privileges and setting system env var
-			// e.g., using pkexec to run a script that modifies /etc/environment or a shell profile
-			// For now, placeholder:
-			// err = os.Setenv(name, value) // This is not system-wide
-			err = fmt.Errorf("Linux implementation pending for system-wide env var setting")
+			err = s.applySystemdEnv(name, value)
 		default:
 			err = fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 		}
@@ -48,3 +66,82 @@ func (s *SystemApp) SetOllamaEnvVar(name string, value string) error {
 func NewSystemApp() *SystemApp {
 	return &SystemApp{}
 }
+
+// DryRunOllamaEnvVar 预览 SetOllamaEnvVar 对系统配置的修改，不实际写入
+func (s *SystemApp) DryRunOllamaEnvVar(name string, value string) (*EnvVarChange, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("dry run is not supported on %s", runtime.GOOS)
+	}
+	return s.systemdEnvChange(name, value)
+}
+
+// 生成 ollama.service 的 drop-in 配置修改
+func (s *SystemApp) systemdEnvChange(name, value string) (*EnvVarChange, error) {
+	if !envNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid environment variable name %q", name)
+	}
+	value, err := envconfig.Validate(name, value)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := util.GetInvoker().CommandWithContext(ctx, "systemctl", "show", "-p", "LoadState", "--value", cmd.SystemdUnit)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", cmd.SystemdUnit, err)
+	}
+	if state := strings.TrimSpace(string(output)); state != "loaded" {
+		return nil, fmt.Errorf("%s is not installed (LoadState=%s)", cmd.SystemdUnit, state)
+	}
+	before, err := os.ReadFile(cmd.SystemdDropIn)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	content := cmd.SetDropInEnv(string(before), name, value)
+	return &EnvVarChange{
+		Path:    cmd.SystemdDropIn,
+		Content: content,
+		Diff:    cmd.Diff(cmd.SystemdDropIn, string(before), content),
+		Changed: content != string(before),
+	}, nil
+}
+
+// 通过 pkexec 以 root 身份写入 drop-in 配置，重新加载并重启服务，失败时恢复原配置
+func (s *SystemApp) applySystemdEnv(name, value string) error {
+	change, err := s.systemdEnvChange(name, value)
+	if err != nil {
+		return err
+	}
+	if !change.Changed {
+		return nil
+	}
+	manual := fmt.Errorf("write the following to %s as root, then run 'systemctl daemon-reload' and 'systemctl restart %s':\n%s",
+		cmd.SystemdDropIn, cmd.SystemdUnit, change.Content)
+	if _, err := exec.LookPath("pkexec"); err != nil {
+		return fmt.Errorf("pkexec not found, %w", manual)
+	}
+	args, err := cmd.SystemdApplyCommand(cmd.SystemdDropIn)
+	if err != nil {
+		return err
+	}
+	// 等待用户在授权对话框中输入密码，写入、重新加载及重启在一次授权中完成，失败时脚本恢复原配置
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if output, err := runPrivileged(ctx, strings.NewReader(change.Content), args...); err != nil {
+		message := strings.TrimSpace(string(output))
+		log.Error().Err(err).Str("output", message).Msg("apply systemd drop-in error")
+		if message != "" {
+			return fmt.Errorf("%s: %w, %v", message, err, manual)
+		}
+		return fmt.Errorf("apply %s: %w, %v", cmd.SystemdDropIn, err, manual)
+	}
+	log.Info().Str("name", name).Str("path", cmd.SystemdDropIn).Msg("ollama service environment updated")
+	return nil
+}
+
+// 通过 pkexec 以 root 身份执行固定的命令，数据只通过参数及标准输入传递
+func runPrivileged(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	command := exec.CommandContext(ctx, "pkexec", args...)
+	command.Stdin = stdin
+	return command.CombinedOutput()
+}
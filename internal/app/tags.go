@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/log"
+	olm "ollama-desktop/internal/ollama"
+	"sort"
+	"strings"
+)
+
+var errModelExists = errors.New("destination model already exists")
+
+func (o *Ollama) Copy(request *olm.CopyRequest) error {
+	err := o.newApiClient().Copy(app.ctx, request)
+	if err != nil {
+		log.Error().Err(err).Msg("copy ollama model error")
+		return err
+	}
+	runtime.EventsEmit(app.ctx, eventModelRefresh)
+	return nil
+}
+
+// modelClient 管理本地模型名称使用的接口
+type modelClient interface {
+	List(ctx context.Context) (*olm.ListResponse, error)
+	Copy(ctx context.Context, req *olm.CopyRequest) error
+	Delete(ctx context.Context, req *olm.DeleteRequest) error
+}
+
+// Rename 重命名模型，先复制再删除原模型，删除失败时回滚
+func (o *Ollama) Rename(request *olm.CopyRequest) error {
+	renamed, err := renameModel(app.ctx, o.newApiClient(), request)
+	if err != nil || !renamed {
+		return err
+	}
+	runtime.EventsEmit(app.ctx, eventModelRefresh)
+	return nil
+}
+
+// 重命名模型，名称相同时不做处理并返回 false
+func renameModel(ctx context.Context, client modelClient, request *olm.CopyRequest) (bool, error) {
+	source, destination := normalizeModelName(request.Source), normalizeModelName(request.Destination)
+	if source == destination {
+		return false, nil
+	}
+	digests, err := modelDigests(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := digests[destination]; ok {
+		return false, errModelExists
+	}
+	if err := client.Copy(ctx, request); err != nil {
+		log.Error().Err(err).Msg("rename ollama model error")
+		return false, err
+	}
+	if err := client.Delete(ctx, &olm.DeleteRequest{Model: request.Source}); err != nil {
+		log.Error().Err(err).Msg("rename ollama model error")
+		if rollbackErr := client.Delete(ctx, &olm.DeleteRequest{Model: request.Destination}); rollbackErr != nil {
+			log.Error().Err(rollbackErr).Msg("rollback renamed ollama model error")
+			return false, fmt.Errorf("rename failed and rollback failed, %s must be removed manually: %w", request.Destination, err)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+type ModelAliases struct {
+	Digest string   `json:"digest"`
+	Names  []string `json:"names"`
+}
+
+// Aliases 列出本地模型，指向同一模型的名称归为一组
+func (o *Ollama) Aliases() ([]*ModelAliases, error) {
+	digests, err := modelDigests(app.ctx, o.newApiClient())
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*ModelAliases)
+	for name, digest := range digests {
+		group, ok := groups[digest]
+		if !ok {
+			group = &ModelAliases{Digest: digest}
+			groups[digest] = group
+		}
+		group.Names = append(group.Names, name)
+	}
+	var list []*ModelAliases
+	for _, group := range groups {
+		sort.Strings(group.Names)
+		list = append(list, group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Names[0] < list[j].Names[0]
+	})
+	return list, nil
+}
+
+type TagRequest struct {
+	Source string `json:"source"`
+	// 需要指向源模型的别名，已指向源模型的别名跳过
+	Add []string `json:"add"`
+	// Overwrite 别名已指向其他模型时重新指向源模型，否则报告 errModelExists
+	Overwrite bool `json:"overwrite"`
+	// 需要删除的别名，仅删除与源模型相同的别名
+	Remove []string `json:"remove"`
+}
+
+type TagResult struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Tag 批量管理模型别名
+func (o *Ollama) Tag(request *TagRequest) ([]*TagResult, error) {
+	results, err := tagModel(app.ctx, o.newApiClient(), request)
+	if err != nil {
+		return nil, err
+	}
+	runtime.EventsEmit(app.ctx, eventModelRefresh)
+	return results, nil
+}
+
+func tagModel(ctx context.Context, client modelClient, request *TagRequest) ([]*TagResult, error) {
+	source := normalizeModelName(request.Source)
+	digests, err := modelDigests(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	sourceDigest, ok := digests[source]
+	if !ok {
+		return nil, fmt.Errorf("model %s not found", request.Source)
+	}
+
+	var results []*TagResult
+	for _, alias := range request.Add {
+		name := normalizeModelName(alias)
+		digest, exists := digests[name]
+		if name == source || exists && digest == sourceDigest {
+			continue
+		}
+		result := &TagResult{Name: name, Action: "add"}
+		if exists && !request.Overwrite {
+			result.Error = fmt.Errorf("%s: %w", name, errModelExists).Error()
+		} else if err := client.Copy(ctx, &olm.CopyRequest{Source: source, Destination: name}); err != nil {
+			log.Error().Err(err).Str("alias", name).Msg("add ollama model alias error")
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	for _, alias := range request.Remove {
+		name := normalizeModelName(alias)
+		if name == source {
+			continue
+		}
+		result := &TagResult{Name: name, Action: "remove"}
+		if digest, ok := digests[name]; !ok || digest != sourceDigest {
+			result.Error = fmt.Sprintf("%s is not an alias of %s", name, source)
+		} else if err := client.Delete(ctx, &olm.DeleteRequest{Model: name}); err != nil {
+			log.Error().Err(err).Str("alias", name).Msg("remove ollama model alias error")
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// 本地模型名称与摘要的对应关系
+func modelDigests(ctx context.Context, client modelClient) (map[string]string, error) {
+	resp, err := client.List(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("list ollama model error")
+		return nil, err
+	}
+	digests := make(map[string]string, len(resp.Models))
+	for _, model := range resp.Models {
+		digests[normalizeModelName(model.Name)] = model.Digest
+	}
+	return digests, nil
+}
+
+// 补全模型默认标签，team-default => team-default:latest
+func normalizeModelName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return name
+	}
+	if i := strings.LastIndex(name, "/"); !strings.Contains(name[i+1:], ":") {
+		name += ":latest"
+	}
+	return name
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"errors"
+	olm "ollama-desktop/internal/ollama"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeModelName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"qwen2", "qwen2:latest"},
+		{" qwen2:7b ", "qwen2:7b"},
+		{"team-default", "team-default:latest"},
+		{"jack/llama", "jack/llama:latest"},
+		{"jack/llama:q4_K_M", "jack/llama:q4_K_M"},
+		{"localhost:5000/team/llama", "localhost:5000/team/llama:latest"},
+		{"localhost:5000/team/llama:v1", "localhost:5000/team/llama:v1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := normalizeModelName(tc.name); actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// 记录调用的模型客户端，deleteErr 中的模型删除失败
+type fakeModelClient struct {
+	models    map[string]string
+	deleteErr map[string]error
+	calls     []string
+}
+
+func (c *fakeModelClient) List(context.Context) (*olm.ListResponse, error) {
+	resp := &olm.ListResponse{}
+	for name, digest := range c.models {
+		resp.Models = append(resp.Models, olm.ListModelResponse{Name: name, Digest: digest})
+	}
+	return resp, nil
+}
+
+func (c *fakeModelClient) Copy(_ context.Context, req *olm.CopyRequest) error {
+	c.calls = append(c.calls, "copy "+req.Source+" "+req.Destination)
+	c.models[normalizeModelName(req.Destination)] = c.models[normalizeModelName(req.Source)]
+	return nil
+}
+
+func (c *fakeModelClient) Delete(_ context.Context, req *olm.DeleteRequest) error {
+	c.calls = append(c.calls, "delete "+req.Model)
+	if err := c.deleteErr[req.Model]; err != nil {
+		return err
+	}
+	delete(c.models, normalizeModelName(req.Model))
+	return nil
+}
+
+func TestRenameModel(t *testing.T) {
+	client := &fakeModelClient{models: map[string]string{"qwen2:latest": "a"}}
+	renamed, err := renameModel(context.Background(), client, &olm.CopyRequest{Source: "qwen2", Destination: "chat"})
+	if err != nil || !renamed {
+		t.Fatalf("expected renamed, got %v %v", renamed, err)
+	}
+	if !reflect.DeepEqual(client.models, map[string]string{"chat:latest": "a"}) {
+		t.Errorf("unexpected models %v", client.models)
+	}
+
+	// 名称相同时不做处理
+	renamed, err = renameModel(context.Background(), client, &olm.CopyRequest{Source: "chat", Destination: "chat:latest"})
+	if err != nil || renamed {
+		t.Errorf("expected no-op, got %v %v", renamed, err)
+	}
+
+	client.models["qwen2:latest"] = "b"
+	if _, err := renameModel(context.Background(), client, &olm.CopyRequest{Source: "qwen2", Destination: "chat"}); !errors.Is(err, errModelExists) {
+		t.Errorf("expected %v, got %v", errModelExists, err)
+	}
+}
+
+func TestRenameModel_Rollback(t *testing.T) {
+	deleteErr := errors.New("model is in use")
+	client := &fakeModelClient{
+		models:    map[string]string{"qwen2:latest": "a"},
+		deleteErr: map[string]error{"qwen2": deleteErr},
+	}
+	_, err := renameModel(context.Background(), client, &olm.CopyRequest{Source: "qwen2", Destination: "chat"})
+	if !errors.Is(err, deleteErr) {
+		t.Fatalf("expected %v, got %v", deleteErr, err)
+	}
+	expected := []string{"copy qwen2 chat", "delete qwen2", "delete chat"}
+	if !reflect.DeepEqual(client.calls, expected) {
+		t.Errorf("expected calls %v, got %v", expected, client.calls)
+	}
+	if !reflect.DeepEqual(client.models, map[string]string{"qwen2:latest": "a"}) {
+		t.Errorf("expected copy rolled back, got %v", client.models)
+	}
+
+	// 回滚失败时提示手动删除
+	client.deleteErr["chat"] = errors.New("connection refused")
+	_, err = renameModel(context.Background(), client, &olm.CopyRequest{Source: "qwen2", Destination: "chat"})
+	if !errors.Is(err, deleteErr) || err.Error() == deleteErr.Error() {
+		t.Errorf("expected rollback failure wrapping %v, got %v", deleteErr, err)
+	}
+}
+
+func TestTagModel(t *testing.T) {
+	client := &fakeModelClient{models: map[string]string{"qwen2:latest": "a", "chat:latest": "a", "llama:latest": "b"}}
+	request := &TagRequest{Source: "qwen2", Add: []string{"chat", "llama", "new"}}
+	results, err := tagModel(context.Background(), client, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 已指向源模型的别名跳过，指向其他模型的别名不覆盖
+	expected := []*TagResult{
+		{Name: "llama:latest", Action: "add", Error: "llama:latest: " + errModelExists.Error()},
+		{Name: "new:latest", Action: "add"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %v, got %v", expected, results)
+	}
+	if !reflect.DeepEqual(client.calls, []string{"copy qwen2:latest new:latest"}) {
+		t.Errorf("unexpected calls %v", client.calls)
+	}
+	if client.models["llama:latest"] != "b" {
+		t.Errorf("expected llama not overwritten, got %v", client.models)
+	}
+
+	request.Overwrite = true
+	if _, err := tagModel(context.Background(), client, request); err != nil {
+		t.Fatal(err)
+	}
+	if client.models["llama:latest"] != "a" {
+		t.Errorf("expected llama overwritten, got %v", client.models)
+	}
+}
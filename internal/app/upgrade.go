@@ -0,0 +1,337 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-version"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"ollama-desktop/internal/config"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/cmd"
+	"ollama-desktop/internal/ollama/upgrade"
+	"ollama-desktop/internal/util"
+	"os"
+	"os/exec"
+	"path/filepath"
+	gorun "runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	eventOllamaUpgrade = "ollama_upgrade"
+
+	upgradeStageDownload = "download"
+	upgradeStageInstall  = "install"
+	upgradeStageSuccess  = "success"
+	upgradeStageError    = "error"
+
+	// 下载进度事件的最小间隔
+	upgradeProgressInterval = 500 * time.Millisecond
+)
+
+var ollamaUpgrade = OllamaUpgrade{}
+
+// OllamaUpgrade 下载并安装新版本 Ollama，同时只允许一个任务
+type OllamaUpgrade struct {
+	cancel   context.CancelFunc
+	progress *UpgradeProgress
+	lock     sync.Mutex
+}
+
+// UpgradePlan 当前平台的升级方案
+type UpgradePlan struct {
+	Version string `json:"version"`
+	Current string `json:"current"`
+	Url     string `json:"url"`
+	Asset   string `json:"asset"`
+	Size    int64  `json:"size"`
+	// Method archive 解压到安装目录，installer 运行系统安装程序
+	Method string `json:"method"`
+	// Prefix 默认安装目录，使用系统安装程序时为空
+	Prefix string `json:"prefix"`
+}
+
+type UpgradeRequest struct {
+	// Prefix 安装目录，为空时使用默认目录
+	Prefix string `json:"prefix"`
+}
+
+// UpgradeProgress 升级进度
+type UpgradeProgress struct {
+	Version   string `json:"version"`
+	Asset     string `json:"asset"`
+	Stage     string `json:"stage"`
+	Completed int64  `json:"completed"`
+	Total     int64  `json:"total"`
+	Message   string `json:"message,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (u *OllamaUpgrade) emit(progress UpgradeProgress) {
+	u.lock.Lock()
+	u.progress = &progress
+	u.lock.Unlock()
+	runtime.EventsEmit(app.ctx, eventOllamaUpgrade, progress)
+}
+
+// 获取最新发布，心跳检测已获取时直接使用
+func (u *OllamaUpgrade) release() (*util.Item, error) {
+	ollama.lock.Lock()
+	item := ollama.lastVersion
+	ollama.lock.Unlock()
+	if item != nil && len(item.Assets) > 0 {
+		return item, nil
+	}
+	release := util.GithubRelease{Http: createHttpClient()}
+	item, err := release.Last("ollama", "ollama")
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, errors.New("no ollama release found")
+	}
+	return item, nil
+}
+
+func (u *OllamaUpgrade) plan() (*UpgradePlan, *util.Item, error) {
+	item, err := u.release()
+	if err != nil {
+		return nil, nil, err
+	}
+	asset, method, err := upgrade.SelectAsset(item, gorun.GOOS, gorun.GOARCH)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan := &UpgradePlan{
+		Version: strings.TrimPrefix(item.Name, "v"),
+		Url:     item.Url,
+		Asset:   asset.Name,
+		Size:    asset.Size,
+		Method:  method,
+	}
+	if status := ollama.Status(); status.Version != "" {
+		plan.Current = status.Version
+	}
+	if method == upgrade.MethodArchive {
+		plan.Prefix = defaultUpgradePrefix()
+	}
+	return plan, item, nil
+}
+
+// 默认安装到当前可执行文件所在位置，未安装时安装到用户目录
+func defaultUpgradePrefix() string {
+	if gorun.GOOS == "darwin" {
+		return "/Applications"
+	}
+	if installation, err := cmd.DetectInstallation(app.ctx); err == nil && installation.Path != "" {
+		if dir := filepath.Dir(installation.Path); filepath.Base(dir) == "bin" {
+			return filepath.Dir(dir)
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".local")
+}
+
+// 判断当前用户能否写入目录，目录不存在时尝试创建
+func dirWritable(dir string) bool {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false
+	}
+	file, err := os.CreateTemp(dir, ".ollama-upgrade-*")
+	if err != nil {
+		return false
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return true
+}
+
+func (u *OllamaUpgrade) start(request *UpgradeRequest) error {
+	plan, item, err := u.plan()
+	if err != nil {
+		return err
+	}
+	if plan.Current != "" {
+		current, err1 := version.NewVersion(plan.Current)
+		last, err2 := version.NewVersion(plan.Version)
+		if err1 == nil && err2 == nil && !last.GreaterThan(current) {
+			return fmt.Errorf("ollama %s is already up to date", plan.Current)
+		}
+	}
+	prefix := plan.Prefix
+	if request != nil && request.Prefix != "" {
+		prefix = request.Prefix
+	}
+	if plan.Method == upgrade.MethodArchive {
+		if prefix == "" || !filepath.IsAbs(prefix) {
+			return fmt.Errorf("invalid install prefix %q", prefix)
+		}
+		prefix = filepath.Clean(prefix)
+	}
+	u.lock.Lock()
+	if u.cancel != nil {
+		u.lock.Unlock()
+		return errors.New("ollama upgrade is already running")
+	}
+	ctx, cancel := context.WithCancel(app.ctx)
+	u.cancel = cancel
+	u.lock.Unlock()
+	go func() {
+		defer func() {
+			u.lock.Lock()
+			u.cancel = nil
+			u.lock.Unlock()
+			cancel()
+		}()
+		progress := UpgradeProgress{Version: plan.Version, Asset: plan.Asset}
+		message, err := u.run(ctx, item, plan, prefix, progress)
+		if err != nil {
+			log.Error().Err(err).Str("version", plan.Version).Str("asset", plan.Asset).Msg("upgrade ollama error")
+			progress.Stage, progress.Error = upgradeStageError, err.Error()
+			u.emit(progress)
+			return
+		}
+		log.Info().Str("version", plan.Version).Str("prefix", prefix).Msg("upgrade ollama")
+		progress.Stage, progress.Message = upgradeStageSuccess, message
+		u.emit(progress)
+		// 重新获取服务版本
+		ollama.resetVersion()
+		go ollama.Heartbeat()
+	}()
+	return nil
+}
+
+// 下载、校验并安装，返回需要提示用户的信息
+func (u *OllamaUpgrade) run(ctx context.Context, item *util.Item, plan *UpgradePlan, prefix string, progress UpgradeProgress) (string, error) {
+	// 下载大文件时不限制总耗时
+	client := createHttpClient()
+	client.Timeout = 0
+	checksum, err := upgrade.Checksum(ctx, client, item, plan.Asset)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(config.UpgradeDirName, os.ModePerm); err != nil {
+		return "", err
+	}
+	archive := filepath.Join(config.UpgradeDirName, plan.Asset)
+	progress.Stage, progress.Total = upgradeStageDownload, plan.Size
+	u.emit(progress)
+	asset := item.Asset(plan.Asset)
+	var emittedAt time.Time
+	err = upgrade.Download(ctx, client, asset.Url, archive, checksum, func(completed, total int64) {
+		if time.Since(emittedAt) < upgradeProgressInterval && completed != total {
+			return
+		}
+		emittedAt = time.Now()
+		progress.Completed = completed
+		if total > 0 {
+			progress.Total = total
+		}
+		u.emit(progress)
+	})
+	if err != nil {
+		return "", err
+	}
+	progress.Stage = upgradeStageInstall
+	u.emit(progress)
+	switch gorun.GOOS {
+	case "windows":
+		// 安装程序会停止正在运行的 Ollama，安装包不删除，由安装程序读取
+		return "the ollama installer has been started", exec.Command(archive).Start()
+	case "darwin":
+		defer os.Remove(archive)
+		return "", upgrade.InstallZip(archive, prefix)
+	default:
+		defer os.Remove(archive)
+		return installLinuxArchive(archive, prefix, checksum)
+	}
+}
+
+// 安装到目录，无写入权限时通过 pkexec 提权，由应用启动的服务无论安装是否成功都会重新启动
+func installLinuxArchive(archive, prefix, checksum string) (message string, err error) {
+	installation, _ := cmd.DetectInstallation(app.ctx)
+	unitActive := installation != nil && installation.UnitState == "active"
+	if _, serving := serve.appliedEnvs(); serving {
+		serve.stop()
+		defer func() {
+			if startErr := serve.start(); startErr != nil && err == nil {
+				err = fmt.Errorf("restart ollama serve: %w", startErr)
+			}
+		}()
+	}
+	if dirWritable(prefix) {
+		if err := upgrade.InstallTarGz(archive, prefix); err != nil {
+			return "", err
+		}
+		if unitActive {
+			message = fmt.Sprintf("restart %s to use the new version", cmd.SystemdUnit)
+		}
+		return message, nil
+	}
+	if _, err := exec.LookPath("pkexec"); err != nil {
+		return "", fmt.Errorf("%s is not writable and pkexec not found, choose a writable prefix", prefix)
+	}
+	args, err := cmd.ArchiveInstallCommand(prefix, checksum, unitActive)
+	if err != nil {
+		return "", err
+	}
+	// 发行包通过标准输入传递，由 root 重新校验后解压
+	file, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	// 等待用户在授权对话框中输入密码
+	ctx, cancel := context.WithTimeout(app.ctx, 5*time.Minute)
+	defer cancel()
+	if output, err := runPrivileged(ctx, file, args...); err != nil {
+		log.Error().Err(err).Str("output", string(output)).Msg("install ollama archive error")
+		return "", fmt.Errorf("install to %s: %w", prefix, err)
+	}
+	return "", nil
+}
+
+// UpgradePlan 当前平台的升级方案
+func (o *Ollama) UpgradePlan() (*UpgradePlan, error) {
+	plan, _, err := ollamaUpgrade.plan()
+	if err != nil {
+		log.Error().Err(err).Msg("get ollama upgrade plan error")
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Upgrade 后台下载并安装最新版本，通过 ollama_upgrade 事件报告进度
+func (o *Ollama) Upgrade(request *UpgradeRequest) error {
+	if err := ollamaUpgrade.start(request); err != nil {
+		log.Error().Err(err).Msg("upgrade ollama error")
+		return err
+	}
+	return nil
+}
+
+// CancelUpgrade 取消正在进行的下载，安装阶段无法取消
+func (o *Ollama) CancelUpgrade() {
+	ollamaUpgrade.lock.Lock()
+	defer ollamaUpgrade.lock.Unlock()
+	if ollamaUpgrade.cancel != nil {
+		ollamaUpgrade.cancel()
+	}
+}
+
+// UpgradeProgress 最近一次升级的进度，未升级时返回空
+func (o *Ollama) UpgradeProgress() *UpgradeProgress {
+	ollamaUpgrade.lock.Lock()
+	defer ollamaUpgrade.lock.Unlock()
+	if ollamaUpgrade.progress == nil {
+		return nil
+	}
+	progress := *ollamaUpgrade.progress
+	return &progress
+}
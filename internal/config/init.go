@@ -26,6 +26,8 @@ var (
 	ConfigFileName = "config/ollama-desktop.json"
 	DbFileName     = "config/ollama-desktop.db"
 	LogFileName    = "log/ollama-desktop.log"
+	ServeLogName   = "log/ollama-serve.log"
+	UpgradeDirName = "upgrade"
 )
 
 // 日志配置
@@ -111,6 +113,8 @@ func initWorkDir() {
 	ConfigFileName = filepath.Join(WorkDir, ConfigFileName)
 	DbFileName = filepath.Join(WorkDir, DbFileName)
 	LogFileName = filepath.Join(WorkDir, LogFileName)
+	ServeLogName = filepath.Join(WorkDir, ServeLogName)
+	UpgradeDirName = filepath.Join(WorkDir, UpgradeDirName)
 }
 
 func initDefaultConfig() {
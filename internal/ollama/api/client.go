@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,6 +21,20 @@ import (
 type Client struct {
 	Base *url.URL
 	Http *http.Client
+	// Headers 每个请求附加的请求头，如反向代理要求的认证信息
+	Headers http.Header
+	Auth    *Auth
+}
+
+func (c *Client) setHeaders(request *http.Request) {
+	for name, values := range c.Headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	if c.Auth != nil {
+		c.Auth.apply(request)
+	}
 }
 
 func checkError(resp *http.Response, body []byte) error {
@@ -67,6 +82,7 @@ func (c *Client) do(ctx context.Context, method, path string, reqData, respData
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Accept", "application/json")
 	request.Header.Set("User-Agent", fmt.Sprintf("ollama-desktop/%s (%s %s) Go/%s", config.BuildVersion, runtime.GOARCH, runtime.GOOS, runtime.Version()))
+	c.setHeaders(request)
 
 	respObj, err := c.Http.Do(request)
 	if err != nil {
@@ -113,6 +129,7 @@ func (c *Client) stream(ctx context.Context, method, path string, data any, fn f
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Accept", "application/x-ndjson")
 	request.Header.Set("User-Agent", fmt.Sprintf("ollama-desktop/%s (%s %s) Go/%s", config.BuildVersion, runtime.GOARCH, runtime.GOOS, runtime.Version()))
+	c.setHeaders(request)
 
 	response, err := c.Http.Do(request)
 	if err != nil {
@@ -328,6 +345,70 @@ func (c *Client) CreateBlob(ctx context.Context, digest string, r io.Reader) err
 	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/blobs/%s", digest), r, nil)
 }
 
+// BlobProgressFunc is a function that [Client.UploadBlob] invokes every time
+// a chunk of the blob has been sent to the service.
+type BlobProgressFunc func(completed, total int64)
+
+type progressReader struct {
+	reader    io.Reader
+	completed int64
+	total     int64
+	fn        BlobProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.completed += int64(n)
+		r.fn(r.completed, r.total)
+	}
+	return n, err
+}
+
+// UploadBlob is like [Client.CreateBlob] but streams r with a known size and
+// reports progress through fn.
+func (c *Client) UploadBlob(ctx context.Context, digest string, r io.Reader, size int64, fn BlobProgressFunc) error {
+	if fn != nil {
+		r = &progressReader{reader: r, total: size, fn: fn}
+	}
+	requestURL := c.Base.JoinPath(fmt.Sprintf("/api/blobs/%s", digest))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL.String(), r)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("User-Agent", fmt.Sprintf("ollama-desktop/%s (%s %s) Go/%s", config.BuildVersion, runtime.GOARCH, runtime.GOOS, runtime.Version()))
+	c.setHeaders(request)
+
+	respObj, err := c.Http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer respObj.Body.Close()
+
+	respBody, err := io.ReadAll(respObj.Body)
+	if err != nil {
+		return err
+	}
+	return checkError(respObj, respBody)
+}
+
+// HasBlob checks whether the blob with the given digest already exists on
+// the server.
+func (c *Client) HasBlob(ctx context.Context, digest string) (bool, error) {
+	err := c.do(ctx, http.MethodHead, fmt.Sprintf("/api/blobs/%s", digest), nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	var statusError ollama.StatusError
+	if errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
+
 // Version returns the Ollama server version as a string.
 func (c *Client) Version(ctx context.Context) (string, error) {
 	var version struct {
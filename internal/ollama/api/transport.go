@@ -0,0 +1,127 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+const (
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+)
+
+// Auth 访问受保护服务（如反向代理）时使用的认证信息
+type Auth struct {
+	// Type bearer 或 basic，为空时不认证
+	Type     string `json:"type"`
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+func (a *Auth) Validate() error {
+	switch a.Type {
+	case "":
+	case AuthBearer:
+		if a.Token == "" {
+			return errors.New("bearer token is required")
+		}
+	case AuthBasic:
+		if a.Username == "" {
+			return errors.New("basic auth username is required")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %q", a.Type)
+	}
+	return nil
+}
+
+func (a *Auth) apply(request *http.Request) {
+	switch a.Type {
+	case AuthBearer:
+		request.Header.Set("Authorization", "Bearer "+a.Token)
+	case AuthBasic:
+		request.SetBasicAuth(a.Username, a.Password)
+	}
+}
+
+// TLSOptions 连接 https 服务时使用的证书配置
+type TLSOptions struct {
+	// CAFile 自定义 CA 证书（PEM），与系统证书一同使用
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile、KeyFile 客户端证书及私钥（PEM）
+	CertFile           string `json:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+func (o TLSOptions) empty() bool {
+	return o == TLSOptions{}
+}
+
+func (o TLSOptions) config() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+var (
+	httpClients = map[TLSOptions]*http.Client{}
+	httpLock    sync.Mutex
+)
+
+// NewHttpClient 按证书配置创建客户端，相同配置复用同一客户端以复用连接
+func NewHttpClient(options TLSOptions) (*http.Client, error) {
+	if options.empty() {
+		return http.DefaultClient, nil
+	}
+	httpLock.Lock()
+	defer httpLock.Unlock()
+	if client, ok := httpClients[options]; ok {
+		return client, nil
+	}
+	config, err := options.config()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	client := &http.Client{Transport: transport}
+	httpClients[options] = client
+	return client, nil
+}
+
+// ResetHttpClients 证书文件内容变化后清除复用的客户端
+func ResetHttpClients() {
+	httpLock.Lock()
+	defer httpLock.Unlock()
+	for options, client := range httpClients {
+		client.CloseIdleConnections()
+		delete(httpClients, options)
+	}
+}
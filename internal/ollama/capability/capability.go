@@ -0,0 +1,103 @@
+// Package capability Ollama 服务各版本支持的功能
+package capability
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	KeepAlive         = "keep_alive"
+	Quantize          = "quantize"
+	Ps                = "ps"
+	Embed             = "embed"
+	Tools             = "tools"
+	StructuredOutputs = "structured_outputs"
+)
+
+// Capability 功能及最低支持的服务版本
+type Capability struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MinVersion  string `json:"minVersion"`
+}
+
+// Table 按最低版本排序的功能表
+var Table = []Capability{
+	{KeepAlive, "Keep models loaded with keep_alive", "0.1.23"},
+	{Quantize, "Quantize models on create", "0.1.35"},
+	{Ps, "List running models (/api/ps)", "0.1.38"},
+	{Embed, "Batch embeddings (/api/embed)", "0.3.0"},
+	{Tools, "Tool calling", "0.3.0"},
+	{StructuredOutputs, "Structured outputs with JSON schema", "0.5.0"},
+}
+
+// Status 功能在当前服务版本下是否可用
+type Status struct {
+	Capability
+	Supported bool `json:"supported"`
+}
+
+// RequirementError 服务版本低于功能要求的最低版本
+type RequirementError struct {
+	Capability Capability
+	Current    string
+}
+
+func (e *RequirementError) Error() string {
+	return fmt.Sprintf("%s requires Ollama >= %s, current version is %s", e.Capability.Description, e.Capability.MinVersion, e.Current)
+}
+
+func lookup(name string) (Capability, bool) {
+	for _, capability := range Table {
+		if capability.Name == name {
+			return capability, true
+		}
+	}
+	return Capability{}, false
+}
+
+// 版本未知或为开发版本(0.0.0)时无法判断，视为支持
+func unknown(server *version.Version) bool {
+	return server == nil || server.Equal(version.Must(version.NewVersion("0.0.0")))
+}
+
+// Check 服务版本不支持功能时返回 RequirementError，未知的功能视为支持
+func Check(name string, server *version.Version) error {
+	capability, ok := lookup(name)
+	if !ok || unknown(server) {
+		return nil
+	}
+	if server.Core().LessThan(version.Must(version.NewVersion(capability.MinVersion))) {
+		return &RequirementError{Capability: capability, Current: server.String()}
+	}
+	return nil
+}
+
+// Supported 服务版本是否支持功能
+func Supported(name string, server *version.Version) bool {
+	return Check(name, server) == nil
+}
+
+// List 所有功能在服务版本下的可用状态
+func List(server *version.Version) []*Status {
+	list := make([]*Status, 0, len(Table))
+	for _, capability := range Table {
+		list = append(list, &Status{Capability: capability, Supported: Supported(capability.Name, server)})
+	}
+	return list
+}
+
+// CheckRequest 聊天或生成请求使用了服务版本不支持的功能时返回 RequirementError，
+// format 为 json 以外的非空值时视为 JSON Schema
+func CheckRequest(tools int, format string, server *version.Version) error {
+	if tools > 0 {
+		if err := Check(Tools, server); err != nil {
+			return err
+		}
+	}
+	if format != "" && format != "json" {
+		return Check(StructuredOutputs, server)
+	}
+	return nil
+}
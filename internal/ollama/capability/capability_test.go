@@ -0,0 +1,79 @@
+package capability
+
+import (
+	"errors"
+	"github.com/hashicorp/go-version"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name      string
+		server    string
+		supported bool
+	}{
+		{Ps, "0.1.37", false},
+		{Ps, "0.1.38", true},
+		{Embed, "0.2.8", false},
+		{Embed, "0.3.0", true},
+		// 预发布版本按正式版本判断
+		{StructuredOutputs, "0.5.0-rc1", true},
+		{StructuredOutputs, "0.4.7", false},
+		{StructuredOutputs, "0.0.0", true},
+		{"unknown", "0.1.0", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name+"@"+tc.server, func(t *testing.T) {
+			err := Check(tc.name, version.Must(version.NewVersion(tc.server)))
+			if (err == nil) != tc.supported {
+				t.Fatalf("expected supported %v, got %v", tc.supported, err)
+			}
+			var requirement *RequirementError
+			if err != nil && !errors.As(err, &requirement) {
+				t.Fatalf("unexpected error type %T", err)
+			}
+		})
+	}
+	if err := Check(Tools, nil); err != nil {
+		t.Errorf("expected unknown version supported, got %v", err)
+	}
+	err := Check(Tools, version.Must(version.NewVersion("0.2.1")))
+	if err == nil || err.Error() != "Tool calling requires Ollama >= 0.3.0, current version is 0.2.1" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	list := List(version.Must(version.NewVersion("0.3.0")))
+	if len(list) != len(Table) {
+		t.Fatalf("unexpected list %v", list)
+	}
+	for _, status := range list {
+		if status.Supported != (status.Name != StructuredOutputs) {
+			t.Errorf("unexpected status %+v", status)
+		}
+	}
+}
+
+func TestCheckRequest(t *testing.T) {
+	schema := `{"type":"object"}`
+	testCases := []struct {
+		tools     int
+		format    string
+		server    string
+		supported bool
+	}{
+		{0, "", "0.1.0", true},
+		{0, "json", "0.1.0", true},
+		{1, "", "0.2.8", false},
+		{1, "json", "0.3.0", true},
+		{0, schema, "0.4.7", false},
+		{1, schema, "0.5.0", true},
+	}
+	for _, tc := range testCases {
+		err := CheckRequest(tc.tools, tc.format, version.Must(version.NewVersion(tc.server)))
+		if (err == nil) != tc.supported {
+			t.Errorf("tools %d format %q on %s: expected supported %v, got %v", tc.tools, tc.format, tc.server, tc.supported, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package capability
+
+import (
+	"fmt"
+	olm "ollama-desktop/internal/ollama"
+	"strings"
+)
+
+const (
+	ModelCompletion = "completion"
+	ModelVision     = "vision"
+	ModelTools      = "tools"
+	ModelEmbedding  = "embedding"
+	ModelInsert     = "insert"
+)
+
+// Model 由 /api/show 推断的模型能力
+type Model struct {
+	Name         string   `json:"name"`
+	Digest       string   `json:"digest"`
+	Capabilities []string `json:"capabilities"`
+	// ContextLength 模型训练时的上下文长度，未知时为 0
+	ContextLength int    `json:"contextLength"`
+	ParameterSize string `json:"parameterSize"`
+	Quantization  string `json:"quantization"`
+}
+
+// FromShow 解析模型信息，服务返回能力列表时直接使用，否则根据模板及模型元数据推断
+func FromShow(name, digest string, resp *olm.ShowResponse) *Model {
+	model := &Model{
+		Name:          name,
+		Digest:        digest,
+		ParameterSize: resp.Details.ParameterSize,
+		Quantization:  resp.Details.QuantizationLevel,
+	}
+	arch, _ := resp.ModelInfo["general.architecture"].(string)
+	if value, ok := resp.ModelInfo[arch+".context_length"].(float64); ok {
+		model.ContextLength = int(value)
+	}
+	if len(resp.Capabilities) > 0 {
+		model.Capabilities = resp.Capabilities
+		return model
+	}
+	// 嵌入模型的元数据包含池化方式
+	if _, ok := resp.ModelInfo[arch+".pooling_type"]; ok {
+		model.Capabilities = append(model.Capabilities, ModelEmbedding)
+	} else {
+		model.Capabilities = append(model.Capabilities, ModelCompletion)
+	}
+	if len(resp.ProjectorInfo) > 0 || hasFamily(resp.Details, "clip") || resp.ModelInfo[arch+".vision.block_count"] != nil {
+		model.Capabilities = append(model.Capabilities, ModelVision)
+	}
+	if strings.Contains(resp.Template, ".Tools") {
+		model.Capabilities = append(model.Capabilities, ModelTools)
+	}
+	if strings.Contains(resp.Template, ".Suffix") {
+		model.Capabilities = append(model.Capabilities, ModelInsert)
+	}
+	return model
+}
+
+func hasFamily(details olm.ModelDetails, family string) bool {
+	for _, item := range details.Families {
+		if item == family {
+			return true
+		}
+	}
+	return false
+}
+
+// Has 模型是否具有指定能力
+func (m *Model) Has(capability string) bool {
+	for _, item := range m.Capabilities {
+		if item == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNumCtx 上下文长度不能超过模型训练时的长度，模型长度未知时不校验
+func (m *Model) ValidateNumCtx(numCtx int) error {
+	if m.ContextLength > 0 && numCtx > m.ContextLength {
+		return fmt.Errorf("num_ctx %d exceeds the context length %d of model %s", numCtx, m.ContextLength, m.Name)
+	}
+	return nil
+}
+
+// ValidateChat 在发送对话请求前校验模型是否支持请求中的图片、工具及上下文长度
+func (m *Model) ValidateChat(request *olm.ChatRequest) error {
+	if !m.Has(ModelCompletion) {
+		return fmt.Errorf("model %s does not support chat", m.Name)
+	}
+	if len(request.Tools) > 0 && !m.Has(ModelTools) {
+		return fmt.Errorf("model %s does not support tools", m.Name)
+	}
+	if !m.Has(ModelVision) {
+		for _, message := range request.Messages {
+			if len(message.Images) > 0 {
+				return fmt.Errorf("model %s does not support images", m.Name)
+			}
+		}
+	}
+	switch numCtx := request.Options["num_ctx"].(type) {
+	case int:
+		return m.ValidateNumCtx(numCtx)
+	case float64:
+		return m.ValidateNumCtx(int(numCtx))
+	}
+	return nil
+}
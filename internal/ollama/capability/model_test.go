@@ -0,0 +1,79 @@
+package capability
+
+import (
+	"encoding/json"
+	olm "ollama-desktop/internal/ollama"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseShow(t *testing.T, content string) *olm.ShowResponse {
+	resp := &olm.ShowResponse{}
+	if err := json.Unmarshal([]byte(content), resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestFromShow(t *testing.T) {
+	testCases := []struct {
+		name         string
+		show         string
+		capabilities []string
+		contextLen   int
+	}{
+		{"llama3.1", `{"template":"{{- if .Tools }}tools{{ end }}","details":{"parameter_size":"8.0B","quantization_level":"Q4_0"},
+			"model_info":{"general.architecture":"llama","llama.context_length":131072}}`,
+			[]string{ModelCompletion, ModelTools}, 131072},
+		{"llava", `{"template":"{{ .Prompt }}","details":{"families":["llama","clip"]},"projector_info":{"clip.has_vision_encoder":true},
+			"model_info":{"general.architecture":"llama","llama.context_length":4096}}`,
+			[]string{ModelCompletion, ModelVision}, 4096},
+		{"nomic-embed-text", `{"template":"{{ .Prompt }}","model_info":{"general.architecture":"nomic-bert","nomic-bert.pooling_type":1,"nomic-bert.context_length":2048}}`,
+			[]string{ModelEmbedding}, 2048},
+		{"qwen2.5-coder", `{"template":"{{- if .Suffix }}<|fim_prefix|>{{ end }}","model_info":{"general.architecture":"qwen2"}}`,
+			[]string{ModelCompletion, ModelInsert}, 0},
+		// 服务返回的能力优先
+		{"gemma3", `{"capabilities":["completion","vision"],"model_info":{"general.architecture":"gemma3","gemma3.context_length":8192}}`,
+			[]string{ModelCompletion, ModelVision}, 8192},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model := FromShow(tc.name, "sha256:abc", parseShow(t, tc.show))
+			if !reflect.DeepEqual(model.Capabilities, tc.capabilities) || model.ContextLength != tc.contextLen {
+				t.Errorf("unexpected model %+v", model)
+			}
+		})
+	}
+	model := FromShow("llama3.1", "", parseShow(t, testCases[0].show))
+	if model.ParameterSize != "8.0B" || model.Quantization != "Q4_0" {
+		t.Errorf("unexpected details %+v", model)
+	}
+}
+
+func TestModel_ValidateChat(t *testing.T) {
+	text := &Model{Name: "llama2", Capabilities: []string{ModelCompletion}, ContextLength: 4096}
+	embedding := &Model{Name: "nomic-embed-text", Capabilities: []string{ModelEmbedding}}
+	vision := &Model{Name: "llava", Capabilities: []string{ModelCompletion, ModelVision}}
+	withImage := []olm.Message{{Role: "user", Content: "what is this", Images: []olm.ImageData{[]byte("png")}}}
+	testCases := []struct {
+		model   *Model
+		request *olm.ChatRequest
+		err     string
+	}{
+		{text, &olm.ChatRequest{Messages: []olm.Message{{Role: "user", Content: "hi"}}}, ""},
+		{embedding, &olm.ChatRequest{}, "does not support chat"},
+		{text, &olm.ChatRequest{Messages: withImage}, "does not support images"},
+		{vision, &olm.ChatRequest{Messages: withImage}, ""},
+		{text, &olm.ChatRequest{Tools: olm.Tools{{Type: "function"}}}, "does not support tools"},
+		{text, &olm.ChatRequest{Options: map[string]interface{}{"num_ctx": 8192}}, "exceeds the context length 4096"},
+		{text, &olm.ChatRequest{Options: map[string]interface{}{"num_ctx": float64(2048)}}, ""},
+		{vision, &olm.ChatRequest{Options: map[string]interface{}{"num_ctx": 1 << 20}}, ""},
+	}
+	for i, tc := range testCases {
+		err := tc.model.ValidateChat(tc.request)
+		if tc.err == "" && err != nil || tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("case %d: expected %q, got %v", i, tc.err, err)
+		}
+	}
+}
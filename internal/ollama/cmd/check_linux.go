@@ -6,13 +6,72 @@ package cmd
 import (
 	"context"
 	"ollama-desktop/internal/util"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
+var (
+	invoker  = util.GetInvoker()
+	lookPath = exec.LookPath
+	// 官方安装脚本及常见包管理器的安装位置，桌面环境启动时 PATH 可能不完整
+	knownPaths = []string{
+		"/usr/local/bin/ollama",
+		"/usr/bin/ollama",
+		"/usr/local/lib/ollama/bin/ollama",
+		"/opt/ollama/bin/ollama",
+		"/snap/bin/ollama",
+		"~/.local/bin/ollama",
+	}
+)
+
+// CheckInstalled 仅查找可执行文件，不启动子进程
 func CheckInstalled(ctx context.Context) (bool, error) {
-	response, err := util.GetInvoker().CommandWithContext(ctx, "command -v ollama >/dev/null 2>&1 || { echo >&2 'not'; }")
-	if err != nil {
-		return false, err
+	return findExecutable() != "", nil
+}
+
+// DetectInstallation 查找 ollama 可执行文件及 systemd 服务
+func DetectInstallation(ctx context.Context) (*Installation, error) {
+	installation := &Installation{}
+	if path := findExecutable(); path != "" {
+		installation.Installed = true
+		installation.Path = path
+		// 版本获取失败时仍视为已安装
+		output, _ := invoker.CommandWithContext(ctx, path, "-v")
+		installation.Version = parseVersion(string(output))
+	}
+	output, err := invoker.CommandWithContext(ctx, "systemctl", "show", "-p", "LoadState", "-p", "ActiveState", SystemdUnit)
+	if err == nil {
+		properties := make(map[string]string)
+		for _, line := range strings.Split(string(output), "\n") {
+			if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+				properties[key] = value
+			}
+		}
+		if properties["LoadState"] == "loaded" {
+			installation.Unit = SystemdUnit
+			installation.UnitState = properties["ActiveState"]
+		}
+	}
+	return installation, nil
+}
+
+func findExecutable() string {
+	if path, err := lookPath("ollama"); err == nil {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	for _, path := range knownPaths {
+		if strings.HasPrefix(path, "~/") {
+			if home == "" {
+				continue
+			}
+			path = filepath.Join(home, path[2:])
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return path
+		}
 	}
-	return !strings.Contains(string(response), "not"), nil
+	return ""
 }
@@ -0,0 +1,117 @@
+//go:build !windows && !darwin
+// +build !windows,!darwin
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeInvoker 按命令名返回预设的输出
+type fakeInvoker struct {
+	outputs map[string]string
+	calls   []string
+}
+
+func (f *fakeInvoker) Command(name string, arg ...string) ([]byte, error) {
+	return f.CommandWithContext(context.Background(), name, arg...)
+}
+
+func (f *fakeInvoker) CommandWithContext(_ context.Context, name string, arg ...string) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(append([]string{name}, arg...), " "))
+	output, ok := f.outputs[filepath.Base(name)]
+	if !ok {
+		return nil, exec.ErrNotFound
+	}
+	return []byte(output), nil
+}
+
+func fakeInstallation(t *testing.T, path string, outputs map[string]string) *fakeInvoker {
+	fake := &fakeInvoker{outputs: outputs}
+	oldInvoker, oldLookPath, oldKnownPaths := invoker, lookPath, knownPaths
+	t.Cleanup(func() {
+		invoker, lookPath, knownPaths = oldInvoker, oldLookPath, oldKnownPaths
+	})
+	invoker = fake
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	knownPaths = []string{filepath.Join(t.TempDir(), "missing", "ollama")}
+	if path != "" {
+		knownPaths = append(knownPaths, path)
+	}
+	return fake
+}
+
+func writeExecutable(t *testing.T, mode os.FileMode) string {
+	path := filepath.Join(t.TempDir(), "ollama")
+	if err := os.WriteFile(path, nil, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDetectInstallation(t *testing.T) {
+	path := writeExecutable(t, 0o755)
+	fake := fakeInstallation(t, path, map[string]string{
+		"ollama":    "ollama version is 0.3.12\n",
+		"systemctl": "LoadState=loaded\nActiveState=active\n",
+	})
+	installation, err := DetectInstallation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Installation{Installed: true, Path: path, Version: "0.3.12", Unit: SystemdUnit, UnitState: "active"}
+	if *installation != expected {
+		t.Errorf("expected %+v, got %+v", expected, *installation)
+	}
+	if len(fake.calls) != 2 || fake.calls[0] != path+" -v" {
+		t.Errorf("unexpected calls %q", fake.calls)
+	}
+}
+
+func TestDetectInstallation_NotRunning(t *testing.T) {
+	path := writeExecutable(t, 0o755)
+	fakeInstallation(t, path, map[string]string{
+		"ollama":    "Warning: could not connect to a running Ollama instance\nWarning: client version is 0.4.0\n",
+		"systemctl": "LoadState=not-found\nActiveState=inactive\n",
+	})
+	installation, _ := DetectInstallation(context.Background())
+	expected := Installation{Installed: true, Path: path, Version: "0.4.0"}
+	if *installation != expected {
+		t.Errorf("expected %+v, got %+v", expected, *installation)
+	}
+}
+
+func TestDetectInstallation_NotInstalled(t *testing.T) {
+	// 没有执行权限的文件不视为已安装，没有 systemctl 时忽略服务状态
+	fakeInstallation(t, writeExecutable(t, 0o644), map[string]string{})
+	installation, err := DetectInstallation(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *installation != (Installation{}) {
+		t.Errorf("unexpected installation %+v", *installation)
+	}
+	if installed, _ := CheckInstalled(context.Background()); installed {
+		t.Error("expected not installed")
+	}
+}
+
+func TestDetectInstallation_LookPath(t *testing.T) {
+	fakeInstallation(t, "", map[string]string{"ollama": "ollama version is 0.3.0"})
+	lookPath = func(file string) (string, error) {
+		if file != "ollama" {
+			return "", errors.New("unexpected file " + file)
+		}
+		return "/opt/bin/ollama", nil
+	}
+	installation, _ := DetectInstallation(context.Background())
+	if installation.Path != "/opt/bin/ollama" || installation.Version != "0.3.0" || installation.Unit != "" {
+		t.Errorf("unexpected installation %+v", *installation)
+	}
+}
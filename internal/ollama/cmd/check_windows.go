@@ -37,3 +37,9 @@ func CheckInstalled(ctx context.Context) (bool, error) {
 
 	return true, nil
 }
+
+// DetectInstallation 目前仅检测是否已安装
+func DetectInstallation(ctx context.Context) (*Installation, error) {
+	installed, err := CheckInstalled(ctx)
+	return &Installation{Installed: installed}, err
+}
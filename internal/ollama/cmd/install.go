@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Installation 本机 Ollama 的安装信息
+type Installation struct {
+	Installed bool `json:"installed"`
+	// Path ollama 可执行文件路径
+	Path string `json:"path,omitempty"`
+	// Version 可执行文件的版本，与运行中服务的版本可能不同
+	Version string `json:"version,omitempty"`
+	// Unit systemd 服务名称，未安装服务时为空
+	Unit string `json:"unit,omitempty"`
+	// UnitState systemd 服务的运行状态，如 active、inactive、failed
+	UnitState string `json:"unitState,omitempty"`
+}
+
+// 匹配 ollama -v 的输出，服务未启动时输出 "Warning: client version is x.y.z"
+var versionPattern = regexp.MustCompile(`version is (\S+)`)
+
+func parseVersion(output string) string {
+	if m := versionPattern.FindStringSubmatch(output); m != nil {
+		return strings.TrimPrefix(m[1], "v")
+	}
+	return ""
+}
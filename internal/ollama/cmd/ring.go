@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"sync"
+)
+
+// RingBuffer 按行保存最近输出的环形缓冲区，可作为 io.Writer 使用
+type RingBuffer struct {
+	lines   []string
+	start   int
+	size    int
+	total   uint64
+	partial []byte
+	lock    sync.Mutex
+}
+
+// NewRingBuffer 创建最多保存 capacity 行的缓冲区
+func NewRingBuffer(capacity int) *RingBuffer {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &RingBuffer{lines: make([]string, capacity)}
+}
+
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	data := p
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			r.partial = append(r.partial, data...)
+			break
+		}
+		line := append(r.partial, data[:i]...)
+		r.partial = nil
+		r.push(string(bytes.TrimRight(line, "\r")))
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+func (r *RingBuffer) push(line string) {
+	r.total++
+	capacity := len(r.lines)
+	if r.size < capacity {
+		r.lines[(r.start+r.size)%capacity] = line
+		r.size++
+		return
+	}
+	r.lines[r.start] = line
+	r.start = (r.start + 1) % capacity
+}
+
+// Lines 按写入顺序返回缓冲区中的行，不包含未换行的内容
+func (r *RingBuffer) Lines() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	lines := make([]string, 0, r.size)
+	for i := 0; i < r.size; i++ {
+		lines = append(lines, r.lines[(r.start+i)%len(r.lines)])
+	}
+	return lines
+}
+
+// Tail 返回最后 n 行
+func (r *RingBuffer) Tail(n int) []string {
+	lines := r.Lines()
+	if n >= 0 && n < len(lines) {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// Since 返回序号 seq 之后写入的行及最新的序号，超出缓冲区的行已丢弃
+func (r *RingBuffer) Since(seq uint64) ([]string, uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if seq >= r.total {
+		return nil, r.total
+	}
+	n := r.total - seq
+	if n > uint64(r.size) {
+		n = uint64(r.size)
+	}
+	lines := make([]string, 0, n)
+	for i := r.size - int(n); i < r.size; i++ {
+		lines = append(lines, r.lines[(r.start+i)%len(r.lines)])
+	}
+	return lines, r.total
+}
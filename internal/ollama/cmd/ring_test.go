@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRingBuffer(t *testing.T) {
+	buffer := NewRingBuffer(3)
+	_, _ = buffer.Write([]byte("one\ntwo\r\nthr"))
+	if lines := buffer.Lines(); !reflect.DeepEqual(lines, []string{"one", "two"}) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	_, _ = buffer.Write([]byte("ee\nfour\nfive\n"))
+	if lines := buffer.Lines(); !reflect.DeepEqual(lines, []string{"three", "four", "five"}) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	if lines := buffer.Tail(2); !reflect.DeepEqual(lines, []string{"four", "five"}) {
+		t.Fatalf("unexpected tail %q", lines)
+	}
+}
+
+func TestRingBuffer_Since(t *testing.T) {
+	buffer := NewRingBuffer(2)
+	_, _ = buffer.Write([]byte("a\nb\n"))
+	lines, seq := buffer.Since(0)
+	if !reflect.DeepEqual(lines, []string{"a", "b"}) || seq != 2 {
+		t.Fatalf("unexpected lines %q, seq %d", lines, seq)
+	}
+	_, _ = buffer.Write([]byte("c\nd\ne\n"))
+	lines, seq = buffer.Since(seq)
+	if !reflect.DeepEqual(lines, []string{"d", "e"}) || seq != 5 {
+		t.Fatalf("unexpected lines %q, seq %d", lines, seq)
+	}
+	if lines, _ = buffer.Since(seq); len(lines) != 0 {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
@@ -2,12 +2,22 @@ package cmd
 
 import (
 	"context"
+	"net/http"
+	"net/url"
 	"ollama-desktop/internal/ollama/api"
+	"runtime"
 	"testing"
 )
 
 func TestStartApp(t *testing.T) {
-	err := StartApp(context.Background(), api.ClientFromConfig())
+	if runtime.GOOS != "windows" && runtime.GOOS != "darwin" {
+		t.Skip("ollama serve is started by Supervisor on this platform")
+	}
+	if installed, _ := CheckInstalled(context.Background()); !installed {
+		t.Skip("ollama is not installed")
+	}
+	client := &api.Client{Base: &url.URL{Scheme: "http", Host: "127.0.0.1:11434"}, Http: http.DefaultClient}
+	err := StartApp(context.Background(), client)
 	if err != nil {
 		t.Fatal(err)
 	}
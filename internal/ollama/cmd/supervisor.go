@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"ollama-desktop/internal/log"
+	"ollama-desktop/internal/ollama/api"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StateStopped    = "stopped"
+	StateStarting   = "starting"
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+)
+
+// 进程持续运行超过该时间后，重启等待时间恢复为最小值
+const stableDuration = time.Minute
+
+var errSupervisorStopped = errors.New("ollama serve supervisor stopped")
+
+// Supervisor 以子进程方式运行 ollama serve，异常退出时按退避时间自动重启
+type Supervisor struct {
+	// Path ollama 可执行文件路径
+	Path string
+	// Args 启动参数，为空时使用 serve
+	Args []string
+	// Env 子进程环境变量，为空时继承当前进程
+	Env []string
+	// Log 输出日志文件，为空时仅保存在缓冲区中
+	Log io.Writer
+	// Client 用于检测服务是否就绪
+	Client       *api.Client
+	ReadyTimeout time.Duration
+	StopTimeout  time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	// OnStateChange 状态变化时回调，err 为导致状态变化的错误
+	OnStateChange func(state string, err error)
+
+	buffer    *RingBuffer
+	lock      sync.Mutex
+	state     string
+	pid       int
+	startedAt time.Time
+	restarts  int
+	lastErr   error
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// SupervisorStatus 子进程运行状态
+type SupervisorStatus struct {
+	State     string    `json:"state"`
+	Pid       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"lastError"`
+}
+
+func NewSupervisor(path string, client *api.Client) *Supervisor {
+	return &Supervisor{
+		Path:         path,
+		Client:       client,
+		ReadyTimeout: 30 * time.Second,
+		StopTimeout:  10 * time.Second,
+		MinBackoff:   time.Second,
+		MaxBackoff:   30 * time.Second,
+		buffer:       NewRingBuffer(1000),
+		state:        StateStopped,
+	}
+}
+
+// Start 启动子进程并等待服务就绪，启动失败时返回错误并停止监管
+func (s *Supervisor) Start(ctx context.Context) error {
+	s.lock.Lock()
+	if s.stop != nil {
+		s.lock.Unlock()
+		return errors.New("ollama serve is already running")
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	s.restarts = 0
+	s.lastErr = nil
+	stop, done := s.stop, s.done
+	s.lock.Unlock()
+
+	ready := make(chan error, 1)
+	go s.run(stop, done, ready)
+	select {
+	case err := <-ready:
+		if err != nil {
+			s.Stop()
+		}
+		return err
+	case <-ctx.Done():
+		s.Stop()
+		return ctx.Err()
+	}
+}
+
+// Stop 停止子进程，未运行时直接返回
+func (s *Supervisor) Stop() {
+	s.lock.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.lock.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Running 是否处于监管中
+func (s *Supervisor) Running() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stop != nil
+}
+
+func (s *Supervisor) Status() SupervisorStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	status := SupervisorStatus{
+		State:     s.state,
+		Pid:       s.pid,
+		StartedAt: s.startedAt,
+		Restarts:  s.restarts,
+	}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	return status
+}
+
+// Lines 返回最近的输出，n 小于 0 时返回全部
+func (s *Supervisor) Lines(n int) []string {
+	return s.buffer.Tail(n)
+}
+
+// LinesSince 返回序号 seq 之后的输出及最新的序号
+func (s *Supervisor) LinesSince(seq uint64) ([]string, uint64) {
+	return s.buffer.Since(seq)
+}
+
+func (s *Supervisor) run(stop, done chan struct{}, ready chan<- error) {
+	defer close(done)
+	backoff := s.MinBackoff
+	for {
+		launched := time.Now()
+		err := s.serve(stop, &ready)
+		if errors.Is(err, errSupervisorStopped) {
+			s.setState(StateStopped, nil)
+			return
+		}
+		// 首次启动失败由 Start 返回错误，不再重启
+		if ready != nil {
+			ready <- err
+			s.setState(StateStopped, err)
+			return
+		}
+		if time.Since(launched) > stableDuration {
+			backoff = s.MinBackoff
+		}
+		s.lock.Lock()
+		s.restarts++
+		s.lock.Unlock()
+		log.Warn().Err(err).Dur("backoff", backoff).Msg("ollama serve exited, restarting")
+		s.setState(StateRestarting, err)
+		select {
+		case <-stop:
+			s.setState(StateStopped, nil)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.MaxBackoff {
+			backoff = s.MaxBackoff
+		}
+	}
+}
+
+// serve 启动一次子进程并等待其退出，就绪后通过 ready 通知 Start
+func (s *Supervisor) serve(stop chan struct{}, ready *chan<- error) error {
+	if *ready != nil {
+		s.setState(StateStarting, nil)
+	}
+	process, err := s.launch()
+	if err != nil {
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- process.Wait()
+	}()
+	if exit, err := s.waitReady(stop, exited); err != nil {
+		if !exit {
+			s.terminate(process, exited)
+		}
+		return err
+	}
+	s.setState(StateRunning, nil)
+	if *ready != nil {
+		*ready <- nil
+		*ready = nil
+	}
+	select {
+	case err := <-exited:
+		return s.exitError(err)
+	case <-stop:
+		s.terminate(process, exited)
+		return errSupervisorStopped
+	}
+}
+
+func (s *Supervisor) launch() (*exec.Cmd, error) {
+	args := s.Args
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	process := exec.Command(s.Path, args...)
+	process.Env = s.Env
+	var out io.Writer = s.buffer
+	if s.Log != nil {
+		out = io.MultiWriter(s.buffer, s.Log)
+	}
+	process.Stdout = out
+	process.Stderr = out
+	// 子进程派生的进程可能继续持有输出管道，避免 Wait 一直阻塞
+	process.WaitDelay = time.Second
+	if err := process.Start(); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.pid = process.Process.Pid
+	s.startedAt = time.Now()
+	s.lock.Unlock()
+	log.Info().Int("pid", process.Process.Pid).Str("path", s.Path).Msg("ollama serve started")
+	return process, nil
+}
+
+// 等待服务就绪，进程退出、超时或停止时返回错误，exit 表示进程已退出
+func (s *Supervisor) waitReady(stop chan struct{}, exited <-chan error) (exit bool, err error) {
+	timeout := time.NewTimer(s.ReadyTimeout)
+	defer timeout.Stop()
+	tick := time.NewTicker(500 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return false, errSupervisorStopped
+		case err := <-exited:
+			return true, s.exitError(err)
+		case <-timeout.C:
+			return false, fmt.Errorf("timed out waiting for ollama serve to start%s", s.tail())
+		case <-tick.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			err := s.Client.Heartbeat(ctx)
+			cancel()
+			if err == nil {
+				return false, nil
+			}
+		}
+	}
+}
+
+func (s *Supervisor) exitError(err error) error {
+	if err == nil {
+		err = errors.New("exit status 0")
+	}
+	return fmt.Errorf("ollama serve exited: %w%s", err, s.tail())
+}
+
+// 最后几行输出，便于定位启动失败的原因
+func (s *Supervisor) tail() string {
+	lines := s.buffer.Tail(5)
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+// 先发送中断信号，超时后强制结束进程
+func (s *Supervisor) terminate(process *exec.Cmd, exited <-chan error) {
+	if err := process.Process.Signal(os.Interrupt); err != nil {
+		_ = process.Process.Kill()
+	}
+	select {
+	case <-exited:
+	case <-time.After(s.StopTimeout):
+		log.Warn().Int("pid", process.Process.Pid).Msg("ollama serve did not stop in time, killing")
+		_ = process.Process.Kill()
+		<-exited
+	}
+	log.Info().Int("pid", process.Process.Pid).Msg("ollama serve stopped")
+}
+
+func (s *Supervisor) setState(state string, err error) {
+	s.lock.Lock()
+	if state == StateStopped {
+		s.pid = 0
+	}
+	if err != nil {
+		s.lastErr = err
+	}
+	changed := s.state != state || err != nil
+	s.state = state
+	s.lock.Unlock()
+	if changed && s.OnStateChange != nil {
+		s.OnStateChange(state, err)
+	}
+}
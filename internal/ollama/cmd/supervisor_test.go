@@ -0,0 +1,104 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"ollama-desktop/internal/ollama/api"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 使用 shell 脚本模拟 ollama serve，脚本启动后创建 ready 文件表示服务就绪
+func fakeServe(t *testing.T, script string) (*Supervisor, string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ollama")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ready := filepath.Join(dir, "ready")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(ready); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	base, _ := url.Parse(server.URL)
+	s := NewSupervisor(path, &api.Client{Base: base, Http: server.Client()})
+	s.Env = append(os.Environ(), "READY="+ready)
+	s.ReadyTimeout = 5 * time.Second
+	s.StopTimeout = 2 * time.Second
+	s.MinBackoff = 10 * time.Millisecond
+	s.MaxBackoff = 50 * time.Millisecond
+	return s, ready
+}
+
+func TestSupervisor_StartStop(t *testing.T) {
+	s, _ := fakeServe(t, `echo "listening $1"; touch "$READY"; trap 'rm -f "$READY"; exit 0' INT TERM; while true; do sleep 0.1; done`)
+	var lock sync.Mutex
+	var states []string
+	s.OnStateChange = func(state string, _ error) {
+		lock.Lock()
+		states = append(states, state)
+		lock.Unlock()
+	}
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if status := s.Status(); status.State != StateRunning || status.Pid == 0 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if err := s.Start(context.Background()); err == nil {
+		t.Fatal("expected already running error")
+	}
+	s.Stop()
+	if s.Running() || s.Status().State != StateStopped {
+		t.Fatalf("unexpected status %+v", s.Status())
+	}
+	if lines := s.Lines(-1); len(lines) == 0 || lines[0] != "listening serve" {
+		t.Fatalf("unexpected output %q", lines)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if strings.Join(states, ",") != "starting,running,stopped" {
+		t.Fatalf("unexpected states %q", states)
+	}
+}
+
+func TestSupervisor_StartFailed(t *testing.T) {
+	s, _ := fakeServe(t, `echo "Error: listen tcp: address already in use" >&2; exit 1`)
+	err := s.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s.Running() {
+		t.Fatal("supervisor should be stopped")
+	}
+}
+
+func TestSupervisor_Restart(t *testing.T) {
+	// 就绪后立即退出，模拟运行中崩溃
+	s, _ := fakeServe(t, `touch "$READY"; sleep 0.6; rm -f "$READY"; exit 2`)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	deadline := time.Now().Add(10 * time.Second)
+	for s.Status().Restarts < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected restarts, status %+v", s.Status())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !strings.Contains(s.Status().LastError, "exit status 2") {
+		t.Fatalf("unexpected last error %q", s.Status().LastError)
+	}
+}
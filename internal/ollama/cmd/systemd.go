@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SystemdUnit Linux 安装脚本创建的 Ollama 服务
+	SystemdUnit = "ollama.service"
+	// SystemdDropIn 用于覆盖服务环境变量的配置文件
+	SystemdDropIn = "/etc/systemd/system/ollama.service.d/override.conf"
+)
+
+// SetDropInEnv 修改 systemd drop-in 配置中 [Service] 段的环境变量，value 为空时删除，保留其他配置
+func SetDropInEnv(content, name, value string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	var result []string
+	inService, serviceFound, written := false, false, false
+	entry := "Environment=" + quoteEnv(name, value)
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			// 离开 [Service] 段前写入
+			if inService && !written && value != "" {
+				// 插入到段末尾的空行之前
+				i := len(result)
+				for i > 0 && strings.TrimSpace(result[i-1]) == "" {
+					i--
+				}
+				result = append(result[:i], append([]string{entry}, result[i:]...)...)
+				written = true
+			}
+			inService = trimmed == "[Service]"
+			serviceFound = serviceFound || inService
+			result = append(result, line)
+			continue
+		}
+		if inService && strings.HasPrefix(trimmed, "Environment=") {
+			var kept []string
+			replaced := false
+			for _, item := range splitEnv(strings.TrimPrefix(trimmed, "Environment=")) {
+				if key, _, _ := strings.Cut(item, "="); key == name {
+					replaced = true
+					continue
+				}
+				kept = append(kept, quoteEnvItem(item))
+			}
+			if replaced && value != "" && !written {
+				kept = append(kept, quoteEnv(name, value))
+				written = true
+			}
+			if len(kept) > 0 {
+				if replaced {
+					line = "Environment=" + strings.Join(kept, " ")
+				}
+				result = append(result, line)
+			}
+			continue
+		}
+		result = append(result, line)
+	}
+	// [Service] 段在末尾或不存在
+	if value != "" && !written {
+		if !serviceFound {
+			result = append(result, "[Service]")
+		}
+		result = append(result, entry)
+	}
+	if len(result) == 0 {
+		return ""
+	}
+	return strings.Join(result, "\n") + "\n"
+}
+
+// DropInEnv 读取 drop-in 配置中 [Service] 段的环境变量
+func DropInEnv(content string) map[string]string {
+	envs := make(map[string]string)
+	inService := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			inService = trimmed == "[Service]"
+			continue
+		}
+		if inService && strings.HasPrefix(trimmed, "Environment=") {
+			for _, item := range splitEnv(strings.TrimPrefix(trimmed, "Environment=")) {
+				if key, value, ok := strings.Cut(item, "="); ok {
+					envs[key] = strings.ReplaceAll(value, "%%", "%")
+				}
+			}
+		}
+	}
+	return envs
+}
+
+// 按空白拆分 Environment= 的值，支持双引号及反斜杠转义
+func splitEnv(value string) []string {
+	var items []string
+	var sb strings.Builder
+	quoted, escaped, has := false, false, false
+	for _, r := range value {
+		switch {
+		case escaped:
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			has = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if has || sb.Len() > 0 {
+				items = append(items, sb.String())
+				sb.Reset()
+				has = false
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if has || sb.Len() > 0 {
+		items = append(items, sb.String())
+	}
+	return items
+}
+
+func quoteEnv(name, value string) string {
+	return quoteEnvItem(name + "=" + strings.ReplaceAll(value, "%", "%%"))
+}
+
+func quoteEnvItem(item string) string {
+	item = strings.ReplaceAll(item, `\`, `\\`)
+	item = strings.ReplaceAll(item, `"`, `\"`)
+	return `"` + item + `"`
+}
+
+// Diff 逐行比较两段文本，输出统一格式的差异
+func Diff(path, old, new string) string {
+	a, b := splitLines(old), splitLines(new)
+	// 最长公共子序列
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString(" " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("-" + a[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+" + b[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(content, "\n"), "\n")
+}
+
+// 以 root 身份执行的命令只在系统目录中查找，不受用户 PATH 影响
+var systemBinDirs = []string{"/usr/bin", "/bin", "/usr/sbin", "/sbin"}
+
+// SystemBinary 在系统目录中查找命令的绝对路径
+func SystemBinary(name string) (string, error) {
+	for _, dir := range systemBinDirs {
+		file := filepath.Join(dir, name)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in %s", name, strings.Join(systemBinDirs, ":"))
+}
+
+// 以 root 身份执行的固定脚本，drop-in 内容从标准输入读取，写入后重新加载并重启服务，
+// 任一步骤失败时恢复原配置并重新加载，参数依次为 drop-in 路径、systemctl 路径及服务名称
+const systemdApplyScript = `set -e
+dropin=$1
+systemctl=$2
+unit=$3
+dir=$(dirname "$dropin")
+mkdir -p "$dir"
+staging=$(mktemp "$dir/.override.XXXXXX")
+backup=
+trap 'rm -f "$staging" "$backup"' EXIT
+cat > "$staging"
+chmod 0644 "$staging"
+if [ -e "$dropin" ]; then
+	backup=$(mktemp "$dir/.override.bak.XXXXXX")
+	cp -p "$dropin" "$backup"
+fi
+mv "$staging" "$dropin"
+if "$systemctl" daemon-reload && "$systemctl" restart "$unit"; then
+	exit 0
+fi
+if [ -n "$backup" ]; then
+	mv "$backup" "$dropin"
+else
+	rm -f "$dropin"
+fi
+"$systemctl" daemon-reload || true
+"$systemctl" restart "$unit" || true
+echo "failed to apply $dropin, previous configuration restored" >&2
+exit 1
+`
+
+// SystemdApplyCommand 以 root 身份写入 drop-in 并重启服务的命令，drop-in 内容通过标准输入传递，
+// 只需一次授权，不执行或安装用户可写的文件
+func SystemdApplyCommand(dropIn string) ([]string, error) {
+	sh, err := SystemBinary("sh")
+	if err != nil {
+		return nil, err
+	}
+	systemctl, err := SystemBinary("systemctl")
+	if err != nil {
+		return nil, err
+	}
+	return []string{sh, "-c", systemdApplyScript, "sh", dropIn, systemctl, SystemdUnit}, nil
+}
+
+// 以 root 身份执行的固定脚本，发行包从标准输入读取到 root 所有的临时目录，重新校验 sha256 后解压，
+// 解压成功后再逐个替换条目，参数依次为安装目录、sha256 及重启服务时使用的 systemctl 路径
+const archiveInstallScript = `set -e
+prefix=$1
+checksum=$2
+systemctl=$3
+mkdir -p "$prefix"
+staging=$(mktemp -d "$prefix/.ollama-upgrade-XXXXXX")
+trap 'rm -rf "$staging"' EXIT
+cat > "$staging/archive.tgz"
+echo "$checksum  $staging/archive.tgz" | sha256sum -c - > /dev/null
+mkdir "$staging/new" "$staging/old"
+tar -xzf "$staging/archive.tgz" -C "$staging/new" --no-same-owner
+cd "$staging/new"
+for entry in */*; do
+	[ -e "$entry" ] || continue
+	mkdir -p "$prefix/$(dirname "$entry")" "$staging/old/$(dirname "$entry")"
+	if [ -e "$prefix/$entry" ] || [ -L "$prefix/$entry" ]; then
+		mv "$prefix/$entry" "$staging/old/$entry"
+	fi
+	mv "$entry" "$prefix/$entry"
+done
+if [ -n "$systemctl" ]; then
+	"$systemctl" restart ` + SystemdUnit + `
+fi
+`
+
+// ArchiveInstallCommand 以 root 身份安装发行包的命令，发行包通过标准输入传递，restart 为 true 时重启服务
+func ArchiveInstallCommand(prefix, checksum string, restart bool) ([]string, error) {
+	sh, err := SystemBinary("sh")
+	if err != nil {
+		return nil, err
+	}
+	systemctl := ""
+	if restart {
+		if systemctl, err = SystemBinary("systemctl"); err != nil {
+			return nil, err
+		}
+	}
+	return []string{sh, "-c", archiveInstallScript, "sh", prefix, checksum, systemctl}, nil
+}
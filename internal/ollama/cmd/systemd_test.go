@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetDropInEnv(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		key      string
+		value    string
+		expected string
+	}{
+		{"empty", "", "OLLAMA_HOST", "0.0.0.0", "[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\n"},
+		{"replace", "[Service]\nEnvironment=\"OLLAMA_HOST=127.0.0.1\"\n", "OLLAMA_HOST", "0.0.0.0",
+			"[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\n"},
+		{"append", "[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\n", "OLLAMA_KEEP_ALIVE", "1h",
+			"[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\nEnvironment=\"OLLAMA_KEEP_ALIVE=1h\"\n"},
+		{"remove", "[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\nEnvironment=\"OLLAMA_KEEP_ALIVE=1h\"\n", "OLLAMA_HOST", "",
+			"[Service]\nEnvironment=\"OLLAMA_KEEP_ALIVE=1h\"\n"},
+		{"multiple on one line", "[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\" OLLAMA_DEBUG=1\n", "OLLAMA_DEBUG", "0",
+			"[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\" \"OLLAMA_DEBUG=0\"\n"},
+		{"other sections", "[Unit]\nDescription=Ollama\n\n[Service]\nUser=ollama\n\n[Install]\nWantedBy=default.target\n", "OLLAMA_MODELS", "/data/50% models",
+			"[Unit]\nDescription=Ollama\n\n[Service]\nUser=ollama\nEnvironment=\"OLLAMA_MODELS=/data/50%% models\"\n\n[Install]\nWantedBy=default.target\n"},
+		{"unit only", "[Unit]\nAfter=network.target\n", "OLLAMA_HOST", "0.0.0.0",
+			"[Unit]\nAfter=network.target\n[Service]\nEnvironment=\"OLLAMA_HOST=0.0.0.0\"\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := SetDropInEnv(tc.content, tc.key, tc.value)
+			if actual != tc.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", tc.expected, actual)
+			}
+			if envs := DropInEnv(actual); tc.value != "" && envs[tc.key] != tc.value {
+				t.Errorf("expected %s=%s, got %q", tc.key, tc.value, envs[tc.key])
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff("override.conf", "[Service]\nEnvironment=\"A=1\"\n", "[Service]\nEnvironment=\"A=2\"\n")
+	expected := "--- override.conf\n+++ override.conf\n [Service]\n-Environment=\"A=1\"\n+Environment=\"A=2\"\n"
+	if diff != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, diff)
+	}
+}
+
+func TestSystemdApplyCommand(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+	for _, name := range []string{"mktemp", "dirname"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s not found", name)
+		}
+	}
+	dir := t.TempDir()
+	oldDirs := systemBinDirs
+	t.Cleanup(func() { systemBinDirs = oldDirs })
+	systemBinDirs = []string{dir}
+	dropIn := filepath.Join(dir, "ollama.service.d", "override.conf")
+	if _, err := SystemdApplyCommand(dropIn); err == nil {
+		t.Fatal("expected error without sh and systemctl")
+	}
+	if err := os.Symlink(sh, filepath.Join(dir, "sh")); err != nil {
+		t.Fatal(err)
+	}
+	// systemctl 记录调用，FAIL_RESTART 文件存在时重启失败
+	systemctl := "#!" + sh + "\necho \"$@\" >> " + filepath.Join(dir, "calls") +
+		"\nif [ \"$1\" = restart ] && [ -e " + filepath.Join(dir, "FAIL_RESTART") + " ]; then exit 1; fi\n"
+	if err := os.WriteFile(filepath.Join(dir, "systemctl"), []byte(systemctl), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(content string) error {
+		args, err := SystemdApplyCommand(dropIn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		command := exec.Command(args[0], args[1:]...)
+		command.Stdin = strings.NewReader(content)
+		output, err := command.CombinedOutput()
+		if err != nil {
+			t.Logf("output: %s", output)
+		}
+		return err
+	}
+	if err := run("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dropIn); string(data) != "v1" {
+		t.Errorf("unexpected drop-in %q", data)
+	}
+	if calls, _ := os.ReadFile(filepath.Join(dir, "calls")); string(calls) != "daemon-reload\nrestart "+SystemdUnit+"\n" {
+		t.Errorf("unexpected calls %q", calls)
+	}
+
+	// 重启失败时恢复原配置
+	_ = os.WriteFile(filepath.Join(dir, "FAIL_RESTART"), nil, 0o644)
+	if err := run("v2"); err == nil {
+		t.Fatal("expected restart error")
+	}
+	if data, _ := os.ReadFile(dropIn); string(data) != "v1" {
+		t.Errorf("expected drop-in restored, got %q", data)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(dir, "ollama.service.d", ".override*")); len(entries) != 0 {
+		t.Errorf("temporary files not removed: %v", entries)
+	}
+	// 原本没有配置时删除
+	_ = os.Remove(dropIn)
+	if err := run("v3"); err == nil {
+		t.Fatal("expected restart error")
+	}
+	if _, err := os.Stat(dropIn); !os.IsNotExist(err) {
+		t.Error("expected drop-in removed")
+	}
+}
+
+func writeReleaseArchive(t *testing.T, binary string) ([]byte, string) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{"./bin/ollama": binary, "./lib/ollama/libggml.so": "lib"} {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		_, _ = tw.Write([]byte(body))
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:])
+}
+
+func TestArchiveInstallCommand(t *testing.T) {
+	for _, name := range []string{"sh", "tar", "sha256sum", "mktemp"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s not found", name)
+		}
+	}
+	prefix := filepath.Join(t.TempDir(), "prefix")
+	if err := os.MkdirAll(filepath.Join(prefix, "lib", "ollama"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(prefix, "lib", "ollama", "stale.so"), nil, 0o644)
+	run := func(archive []byte, checksum string) error {
+		args, err := ArchiveInstallCommand(prefix, checksum, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		command := exec.Command(args[0], args[1:]...)
+		command.Stdin = bytes.NewReader(archive)
+		output, err := command.CombinedOutput()
+		if err != nil {
+			t.Logf("output: %s", output)
+		}
+		return err
+	}
+	archive, checksum := writeReleaseArchive(t, "v1")
+	// 校验失败时不修改安装目录
+	if err := run(archive, strings.Repeat("0", 64)); err == nil {
+		t.Fatal("expected checksum error")
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "lib", "ollama", "stale.so")); err != nil {
+		t.Fatal("expected existing install untouched")
+	}
+	if err := run(archive, checksum); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(prefix, "bin", "ollama")); string(data) != "v1" {
+		t.Errorf("unexpected binary %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "lib", "ollama", "stale.so")); !os.IsNotExist(err) {
+		t.Error("expected old lib/ollama replaced")
+	}
+	if entries, _ := filepath.Glob(filepath.Join(prefix, ".ollama-upgrade-*")); len(entries) != 0 {
+		t.Errorf("staging not removed: %v", entries)
+	}
+}
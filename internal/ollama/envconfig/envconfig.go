@@ -0,0 +1,192 @@
+// Package envconfig Ollama 服务环境变量的校验
+package envconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindBool
+	kindUint
+	kindDuration
+	kindHost
+	kindPath
+	kindURL
+	kindKVCacheType
+)
+
+var kinds = map[string]kind{
+	"OLLAMA_DEBUG":             kindBool,
+	"OLLAMA_FLASH_ATTENTION":   kindBool,
+	"OLLAMA_KV_CACHE_TYPE":     kindKVCacheType,
+	"OLLAMA_GPU_OVERHEAD":      kindUint,
+	"OLLAMA_HOST":              kindHost,
+	"OLLAMA_KEEP_ALIVE":        kindDuration,
+	"OLLAMA_LOAD_TIMEOUT":      kindDuration,
+	"OLLAMA_MAX_LOADED_MODELS": kindUint,
+	"OLLAMA_MAX_QUEUE":         kindUint,
+	"OLLAMA_MODELS":            kindPath,
+	"OLLAMA_NOHISTORY":         kindBool,
+	"OLLAMA_NOPRUNE":           kindBool,
+	"OLLAMA_NUM_PARALLEL":      kindUint,
+	"OLLAMA_SCHED_SPREAD":      kindBool,
+	"OLLAMA_MULTIUSER_CACHE":   kindBool,
+	"OLLAMA_INTEL_GPU":         kindBool,
+	"HTTP_PROXY":               kindURL,
+	"HTTPS_PROXY":              kindURL,
+	"http_proxy":               kindURL,
+	"https_proxy":              kindURL,
+}
+
+var kvCacheTypes = []string{"f16", "q8_0", "q4_0"}
+
+// Clean 去除值两端的空白及引号
+func Clean(value string) string {
+	return strings.Trim(strings.TrimSpace(value), "\"'")
+}
+
+// Validate 校验环境变量的值，返回清理后的值，空值表示使用默认值
+func Validate(name, value string) (string, error) {
+	value = Clean(value)
+	if value == "" {
+		return "", nil
+	}
+	var err error
+	switch kinds[name] {
+	case kindBool:
+		_, err = strconv.ParseBool(value)
+	case kindUint:
+		_, err = strconv.ParseUint(value, 10, 64)
+	case kindDuration:
+		err = validateDuration(value)
+	case kindHost:
+		err = validateHost(value)
+	case kindPath:
+		value, err = validatePath(value)
+	case kindURL:
+		err = validateURL(value)
+	case kindKVCacheType:
+		err = fmt.Errorf("must be one of %s", strings.Join(kvCacheTypes, ", "))
+		for _, t := range kvCacheTypes {
+			if value == t {
+				err = nil
+			}
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	return value, nil
+}
+
+// 与 Ollama 一致，支持 5m、1h 等时长格式或整数秒，负数表示不限制
+func validateDuration(value string) error {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return nil
+	}
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("must be a duration like 5m or a number of seconds")
+	}
+	return nil
+}
+
+// 格式为 [scheme://]host[:port]
+func validateHost(value string) error {
+	hostport := value
+	if scheme, rest, ok := strings.Cut(value, "://"); ok {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("unsupported scheme %s", scheme)
+		}
+		hostport = rest
+	}
+	hostport = strings.TrimSuffix(hostport, "/")
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		// 未指定端口
+		host, port = strings.Trim(hostport, "[]"), ""
+	}
+	if strings.ContainsAny(host, "/ ") {
+		return fmt.Errorf("invalid host %s", host)
+	}
+	if port != "" {
+		if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+			return fmt.Errorf("invalid port %s", port)
+		}
+	}
+	return nil
+}
+
+// 必须为绝对路径，路径已存在时必须为目录
+func validatePath(value string) (string, error) {
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		value = filepath.Join(home, value[1:])
+	}
+	if !filepath.IsAbs(value) {
+		return "", fmt.Errorf("must be an absolute path")
+	}
+	value = filepath.Clean(value)
+	info, err := os.Stat(value)
+	if err == nil && !info.IsDir() {
+		return "", fmt.Errorf("not a directory")
+	}
+	return value, nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be a url like http://host:port")
+	}
+	return nil
+}
+
+// Port 返回 OLLAMA_HOST 中的端口，未指定时与 Ollama 一致按协议使用默认端口
+func Port(host string) string {
+	defaultPort := "11434"
+	if scheme, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+		switch scheme {
+		case "http":
+			defaultPort = "80"
+		case "https":
+			defaultPort = "443"
+		}
+	}
+	if _, port, err := net.SplitHostPort(strings.TrimSuffix(host, "/")); err == nil {
+		return port
+	}
+	return defaultPort
+}
+
+// ListenAddress 返回 ollama serve 按 OLLAMA_HOST 监听的地址，未指定主机时与 Ollama 一致使用 127.0.0.1
+func ListenAddress(host string) string {
+	port := Port(host)
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	host = strings.TrimSuffix(host, "/")
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return net.JoinHostPort(hostname, port)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
@@ -0,0 +1,81 @@
+package envconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"OLLAMA_KEEP_ALIVE", "5m", true},
+		{"OLLAMA_KEEP_ALIVE", "-1", true},
+		{"OLLAMA_KEEP_ALIVE", "3600", true},
+		{"OLLAMA_KEEP_ALIVE", "five minutes", false},
+		{"OLLAMA_NUM_PARALLEL", "4", true},
+		{"OLLAMA_NUM_PARALLEL", "-1", false},
+		{"OLLAMA_HOST", "0.0.0.0", true},
+		{"OLLAMA_HOST", "\"127.0.0.1:11435\"", true},
+		{"OLLAMA_HOST", "http://[::1]:11434", true},
+		{"OLLAMA_HOST", "localhost:70000", false},
+		{"OLLAMA_HOST", "ftp://localhost", false},
+		{"OLLAMA_MODELS", t.TempDir(), true},
+		{"OLLAMA_MODELS", "models", false},
+		{"OLLAMA_MODELS", file, false},
+		{"OLLAMA_FLASH_ATTENTION", "true", true},
+		{"OLLAMA_FLASH_ATTENTION", "yes", false},
+		{"OLLAMA_KV_CACHE_TYPE", "q8_0", true},
+		{"OLLAMA_KV_CACHE_TYPE", "q5_1", false},
+		{"HTTPS_PROXY", "http://proxy:3128", true},
+		{"HTTPS_PROXY", "proxy:3128", false},
+		{"OLLAMA_ORIGINS", "*", true},
+		{"OLLAMA_NUM_PARALLEL", "  ", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name+"="+tc.value, func(t *testing.T) {
+			_, err := Validate(tc.name, tc.value)
+			if (err == nil) != tc.valid {
+				t.Errorf("expected valid %v, got error %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestPort(t *testing.T) {
+	for host, expected := range map[string]string{
+		"0.0.0.0":               "11434",
+		"127.0.0.1:8080":        "8080",
+		"https://example.com/":  "443",
+		"http://[::1]:11435":    "11435",
+		"http://localhost:9000": "9000",
+	} {
+		if port := Port(host); port != expected {
+			t.Errorf("%s: expected %s, got %s", host, expected, port)
+		}
+	}
+}
+
+func TestListenAddress(t *testing.T) {
+	for host, expected := range map[string]string{
+		"":                      "127.0.0.1:11434",
+		"0.0.0.0":               "0.0.0.0:11434",
+		":8080":                 ":8080",
+		"127.0.0.1:8080":        "127.0.0.1:8080",
+		"https://example.com/":  "example.com:443",
+		"http://[::1]:11435":    "[::1]:11435",
+		"[::1]":                 "[::1]:11434",
+		"http://localhost:9000": "localhost:9000",
+	} {
+		if address := ListenAddress(host); address != expected {
+			t.Errorf("%q: expected %s, got %s", host, expected, address)
+		}
+	}
+}
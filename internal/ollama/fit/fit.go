@@ -0,0 +1,202 @@
+package fit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Level 模型在当前硬件上的运行方式
+type Level string
+
+const (
+	FitsGPU  Level = "gpu"
+	FitsRAM  Level = "ram"
+	TooLarge Level = "too_large"
+)
+
+// DefaultNumCtx ollama 默认的上下文长度
+const DefaultNumCtx = 2048
+
+// 加载模型时除权重、KV 缓存外的额外开销
+const overhead = 512 * 1024 * 1024
+
+var ErrUnsupported = errors.New("reading system memory is not supported on this platform")
+
+// Memory 系统内存，单位字节
+type Memory struct {
+	Total     uint64 `json:"total"`
+	Available uint64 `json:"available"`
+}
+
+// ParseMemInfo 解析 /proc/meminfo 格式的内容
+func ParseMemInfo(r io.Reader) (*Memory, error) {
+	memory := &Memory{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// 格式：MemTotal:       16318412 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 2 && strings.EqualFold(fields[2], "kB") {
+			value *= 1024
+		}
+		switch fields[0] {
+		case "MemTotal:":
+			memory.Total = value
+		case "MemAvailable:":
+			memory.Available = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if memory.Total == 0 {
+		return nil, errors.New("MemTotal not found in meminfo")
+	}
+	// 旧内核不提供 MemAvailable
+	if memory.Available == 0 {
+		memory.Available = memory.Total
+	}
+	return memory, nil
+}
+
+// Tag 待评估的模型标签
+type Tag struct {
+	Name string
+	// 模型文件大小，单位字节
+	Size int64
+	// 参数量，如 7.6B，可为空
+	ParameterSize string
+	// 量化类型，如 Q4_K_M，可为空
+	Quantization string
+}
+
+// Result 标签的评估结果
+type Result struct {
+	Tag string `json:"tag"`
+	// 模型文件大小
+	Size int64 `json:"size"`
+	// 按指定上下文长度运行需要的内存
+	Required int64 `json:"required"`
+	Level    Level `json:"level"`
+	// 在所选设备上可容纳的最大上下文长度，too_large 时为 0
+	MaxContext int `json:"maxContext"`
+	// 指定上下文长度下的剩余内存
+	Headroom int64 `json:"headroom"`
+}
+
+// Evaluate 估算标签运行所需内存并判断能否放入显存或内存，vram、ram 为 0 时视为不可用
+func Evaluate(tag Tag, numCtx int, vram, ram uint64) *Result {
+	if numCtx <= 0 {
+		numCtx = DefaultNumCtx
+	}
+	perToken := kvBytesPerToken(tag)
+	required := tag.Size + overhead + perToken*int64(numCtx)
+	result := &Result{
+		Tag:      tag.Name,
+		Size:     tag.Size,
+		Required: required,
+		Level:    TooLarge,
+	}
+	var capacity int64
+	switch {
+	case vram > 0 && required <= int64(vram):
+		result.Level = FitsGPU
+		capacity = int64(vram)
+	case ram > 0 && required <= int64(ram):
+		result.Level = FitsRAM
+		capacity = int64(ram)
+	default:
+		return result
+	}
+	result.Headroom = capacity - required
+	result.MaxContext = int((capacity - tag.Size - overhead) / perToken)
+	return result
+}
+
+// 估算每个上下文 token 的 KV 缓存大小（f16）
+// 经验值：约 64KiB / 十亿参数，如 7B 模型 2048 上下文约占用 1GiB
+func kvBytesPerToken(tag Tag) int64 {
+	params := parseParameters(tag.ParameterSize)
+	if params == 0 {
+		params = parseParameters(tag.Name)
+	}
+	if params == 0 {
+		// 根据量化位数由文件大小反推参数量
+		params = float64(tag.Size) * 8 / quantizationBits(tag.Quantization, tag.Name) / 1e9
+	}
+	perToken := int64(params * 64 * 1024)
+	if perToken < 16*1024 {
+		perToken = 16 * 1024
+	}
+	return perToken
+}
+
+// 解析参数量，单位十亿，如 7.6B、500M、8x7b
+func parseParameters(s string) float64 {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		s = s[i+1:]
+	}
+	// 标签形如 7b-instruct-q4_K_M，仅取第一段
+	if i := strings.IndexAny(s, "-_"); i >= 0 {
+		s = s[:i]
+	}
+	multiplier := 1.0
+	if i := strings.Index(s, "x"); i > 0 {
+		experts, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0
+		}
+		multiplier = experts
+		s = s[i+1:]
+	}
+	var unit float64
+	switch {
+	case strings.HasSuffix(s, "b"):
+		unit = 1
+	case strings.HasSuffix(s, "m"):
+		unit = 0.001
+	default:
+		return 0
+	}
+	value, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0
+	}
+	return multiplier * value * unit
+}
+
+// 量化类型对应的平均位数，未知时按 Q4 计算
+func quantizationBits(values ...string) float64 {
+	for _, value := range values {
+		value = strings.ToLower(value)
+		switch {
+		case strings.Contains(value, "f32"):
+			return 32
+		case strings.Contains(value, "f16"):
+			return 16
+		case strings.Contains(value, "q8"):
+			return 8.5
+		case strings.Contains(value, "q6"):
+			return 6.6
+		case strings.Contains(value, "q5"):
+			return 5.5
+		case strings.Contains(value, "q4"):
+			return 4.5
+		case strings.Contains(value, "q3"):
+			return 3.5
+		case strings.Contains(value, "q2"):
+			return 2.6
+		}
+	}
+	return 4.5
+}
@@ -0,0 +1,80 @@
+package fit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMemInfo(t *testing.T) {
+	content := `MemTotal:       16318412 kB
+MemFree:         1203456 kB
+MemAvailable:    9876543 kB
+Buffers:          123456 kB
+`
+	memory, err := ParseMemInfo(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if memory.Total != 16318412*1024 || memory.Available != 9876543*1024 {
+		t.Errorf("unexpected memory %+v", memory)
+	}
+
+	if _, err := ParseMemInfo(strings.NewReader("Buffers: 1 kB\n")); err == nil {
+		t.Error("expected error without MemTotal")
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected float64
+	}{
+		{"7.6B", 7.6},
+		{"7b-instruct-q4_K_M", 7},
+		{"qwen2:0.5b", 0.5},
+		{"500M", 0.5},
+		{"8x7b", 56},
+		{"latest", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if params := parseParameters(tc.input); params != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, params)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+	testCases := []struct {
+		name     string
+		tag      Tag
+		vram     uint64
+		ram      uint64
+		expected Level
+	}{
+		{"gpu", Tag{Name: "7b-q4_K_M", Size: 4 * gib}, 8 * gib, 16 * gib, FitsGPU},
+		{"ram", Tag{Name: "7b-q8_0", Size: 8 * gib}, 8 * gib, 16 * gib, FitsRAM},
+		{"no gpu", Tag{Name: "7b-q4_K_M", Size: 4 * gib}, 0, 16 * gib, FitsRAM},
+		{"too large", Tag{Name: "70b", Size: 40 * gib}, 8 * gib, 16 * gib, TooLarge},
+		{"unknown parameters", Tag{Name: "latest", Size: 4 * gib, Quantization: "Q4_0"}, 8 * gib, 16 * gib, FitsGPU},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Evaluate(tc.tag, 0, tc.vram, tc.ram)
+			if result.Level != tc.expected {
+				t.Fatalf("expected %s, got %+v", tc.expected, result)
+			}
+			if result.Level == TooLarge {
+				if result.MaxContext != 0 {
+					t.Errorf("unexpected max context %d", result.MaxContext)
+				}
+				return
+			}
+			if result.MaxContext < DefaultNumCtx || result.Headroom < 0 {
+				t.Errorf("unexpected headroom %+v", result)
+			}
+		})
+	}
+}
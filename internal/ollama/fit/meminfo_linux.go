@@ -0,0 +1,15 @@
+//go:build linux
+
+package fit
+
+import "os"
+
+// SystemMemory 读取系统内存
+func SystemMemory() (*Memory, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseMemInfo(file)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package fit
+
+// SystemMemory 读取系统内存
+func SystemMemory() (*Memory, error) {
+	return nil, ErrUnsupported
+}
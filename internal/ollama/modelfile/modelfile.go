@@ -0,0 +1,215 @@
+package modelfile
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"ollama-desktop/internal/ollama"
+	"strings"
+)
+
+const (
+	CommandFrom      = "from"
+	CommandAdapter   = "adapter"
+	CommandParameter = "parameter"
+	CommandTemplate  = "template"
+	CommandSystem    = "system"
+	CommandLicense   = "license"
+	CommandMessage   = "message"
+)
+
+var (
+	ErrMissingFrom     = errors.New("no FROM line for the model was specified")
+	ErrDuplicateFrom   = errors.New("only one FROM line is allowed")
+	ErrUnterminated    = errors.New("unterminated multiline string")
+	ErrMissingArgument = errors.New("missing argument")
+)
+
+// Command Modelfile 中的一条指令
+type Command struct {
+	Name string `json:"name"`
+	Args string `json:"args"`
+	// 参数名称，仅 PARAMETER、MESSAGE 有效
+	Key  string `json:"key,omitempty"`
+	Line int    `json:"line"`
+}
+
+func (c Command) String() string {
+	switch c.Name {
+	case CommandParameter, CommandMessage:
+		return fmt.Sprintf("%s %s %s", strings.ToUpper(c.Name), c.Key, quote(c.Args))
+	case CommandFrom, CommandAdapter:
+		return fmt.Sprintf("%s %s", strings.ToUpper(c.Name), c.Args)
+	default:
+		return fmt.Sprintf("%s %s", strings.ToUpper(c.Name), quote(c.Args))
+	}
+}
+
+// ParseError 解析错误，包含出错的行号
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// File 解析后的 Modelfile
+type File struct {
+	Commands []Command `json:"commands"`
+}
+
+func (f *File) String() string {
+	var sb strings.Builder
+	for _, cmd := range f.Commands {
+		sb.WriteString(cmd.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// From 返回 FROM 指令的值
+func (f *File) From() string {
+	for _, cmd := range f.Commands {
+		if cmd.Name == CommandFrom {
+			return cmd.Args
+		}
+	}
+	return ""
+}
+
+// Parse 解析 Modelfile 内容
+func Parse(r io.Reader) (*File, error) {
+	var commands []Command
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		start := line
+		name, rest, _ := strings.Cut(text, " ")
+		name = strings.ToLower(name)
+		rest = strings.TrimSpace(rest)
+
+		var key string
+		switch name {
+		case CommandFrom, CommandAdapter, CommandTemplate, CommandSystem, CommandLicense:
+		case CommandParameter, CommandMessage:
+			key, rest, _ = strings.Cut(rest, " ")
+			key = strings.TrimSpace(key)
+			rest = strings.TrimSpace(rest)
+			if key == "" {
+				return nil, &ParseError{Line: start, Err: ErrMissingArgument}
+			}
+		default:
+			return nil, &ParseError{Line: start, Err: fmt.Errorf("unknown command %q", name)}
+		}
+
+		// 多行字符串
+		if strings.HasPrefix(rest, `"""`) {
+			value := rest[3:]
+			for !strings.HasSuffix(strings.TrimRight(value, " \t"), `"""`) {
+				if !scanner.Scan() {
+					return nil, &ParseError{Line: start, Err: ErrUnterminated}
+				}
+				line++
+				value += "\n" + scanner.Text()
+			}
+			rest = strings.TrimSuffix(strings.TrimRight(value, " \t"), `"""`)
+		} else {
+			rest = unquote(rest)
+		}
+		if rest == "" && name != CommandSystem && name != CommandTemplate {
+			return nil, &ParseError{Line: start, Err: ErrMissingArgument}
+		}
+		commands = append(commands, Command{Name: name, Args: rest, Key: key, Line: start})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &File{Commands: commands}, nil
+}
+
+// Validate 在本地校验 Modelfile 指令
+func (f *File) Validate() error {
+	from := 0
+	for _, cmd := range f.Commands {
+		switch cmd.Name {
+		case CommandFrom:
+			from++
+			if from > 1 {
+				return &ParseError{Line: cmd.Line, Err: ErrDuplicateFrom}
+			}
+			// Build 生成的 FROM 可能没有指定模型
+			if strings.TrimSpace(cmd.Args) == "" {
+				return &ParseError{Line: cmd.Line, Err: ErrMissingFrom}
+			}
+		case CommandParameter:
+			if _, err := ollama.FormatParams(map[string][]string{cmd.Key: {cmd.Args}}); err != nil {
+				return &ParseError{Line: cmd.Line, Err: err}
+			}
+		case CommandMessage:
+			switch strings.ToLower(cmd.Key) {
+			case "system", "user", "assistant":
+			default:
+				return &ParseError{Line: cmd.Line, Err: fmt.Errorf("invalid message role %q", cmd.Key)}
+			}
+		}
+	}
+	if from == 0 {
+		return ErrMissingFrom
+	}
+	return nil
+}
+
+// Parameter 模型参数
+type Parameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Build 根据基础模型、系统提示词、参数及模板生成 Modelfile
+func Build(from, system, template string, parameters []Parameter) *File {
+	file := &File{}
+	file.Commands = append(file.Commands, Command{Name: CommandFrom, Args: from})
+	for _, parameter := range parameters {
+		if parameter.Name == "" || parameter.Value == "" {
+			continue
+		}
+		file.Commands = append(file.Commands, Command{Name: CommandParameter, Key: parameter.Name, Args: parameter.Value})
+	}
+	if template != "" {
+		file.Commands = append(file.Commands, Command{Name: CommandTemplate, Args: template})
+	}
+	if system != "" {
+		file.Commands = append(file.Commands, Command{Name: CommandSystem, Args: system})
+	}
+	return file
+}
+
+func quote(s string) string {
+	if strings.ContainsAny(s, "\n\"") || strings.HasPrefix(s, " ") || strings.HasSuffix(s, " ") {
+		return `"""` + s + `"""`
+	}
+	if strings.Contains(s, " ") || s == "" {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
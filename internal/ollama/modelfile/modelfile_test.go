@@ -0,0 +1,107 @@
+package modelfile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `# comment
+FROM llama3.1:8b
+PARAMETER temperature 0.7
+PARAMETER stop "<|eot_id|>"
+TEMPLATE """{{ .System }}
+{{ .Prompt }}"""
+SYSTEM You are a helpful assistant.
+MESSAGE user hello
+`
+	file, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Commands) != 6 {
+		t.Fatalf("expected 6 commands, got %d", len(file.Commands))
+	}
+	if file.From() != "llama3.1:8b" {
+		t.Errorf("unexpected from %q", file.From())
+	}
+	if file.Commands[2].Args != "<|eot_id|>" {
+		t.Errorf("unexpected stop %q", file.Commands[2].Args)
+	}
+	if file.Commands[3].Args != "{{ .System }}\n{{ .Prompt }}" {
+		t.Errorf("unexpected template %q", file.Commands[3].Args)
+	}
+	if file.Commands[4].Line != 7 {
+		t.Errorf("unexpected line %d", file.Commands[4].Line)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		err   error
+		line  int
+	}{
+		{"missing from", "SYSTEM hi", ErrMissingFrom, 0},
+		{"duplicate from", "FROM a\nFROM b", ErrDuplicateFrom, 2},
+		{"unknown parameter", "FROM a\nPARAMETER foo 1", nil, 2},
+		{"invalid parameter", "FROM a\nPARAMETER num_ctx abc", nil, 2},
+		{"invalid role", "FROM a\nMESSAGE tool hi", nil, 2},
+		{"unknown command", "FROM a\nRUN echo", nil, 2},
+		{"unterminated", "FROM a\nSYSTEM \"\"\"hi", ErrUnterminated, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := Parse(strings.NewReader(tc.input))
+			if err == nil {
+				err = file.Validate()
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+			var parseErr *ParseError
+			if tc.line > 0 && (!errors.As(err, &parseErr) || parseErr.Line != tc.line) {
+				t.Errorf("expected error at line %d, got %v", tc.line, err)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	file := Build("qwen2:7b", "Answer in \"Chinese\".", "", []Parameter{
+		{Name: "num_ctx", Value: "8192"},
+		{Name: "stop", Value: "<|im_end|>"},
+		{Name: "top_k", Value: ""},
+	})
+	expected := "FROM qwen2:7b\nPARAMETER num_ctx 8192\nPARAMETER stop <|im_end|>\nSYSTEM \"\"\"Answer in \"Chinese\".\"\"\"\n"
+	if file.String() != expected {
+		t.Fatalf("unexpected modelfile:\n%s", file.String())
+	}
+	parsed, err := Parse(strings.NewReader(file.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := parsed.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Commands[3].Args != "Answer in \"Chinese\"." {
+		t.Errorf("unexpected system %q", parsed.Commands[3].Args)
+	}
+}
+
+func TestBuild_MissingFrom(t *testing.T) {
+	for _, from := range []string{"", "  "} {
+		if err := Build(from, "hi", "", nil).Validate(); !errors.Is(err, ErrMissingFrom) {
+			t.Errorf("expected %v for %q, got %v", ErrMissingFrom, from, err)
+		}
+	}
+}
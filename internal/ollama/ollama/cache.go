@@ -0,0 +1,43 @@
+package ollama
+
+import (
+	"context"
+	"time"
+)
+
+// 页面缓存有效期
+const (
+	SearchTTL    = 10 * time.Minute
+	LibraryTTL   = time.Hour
+	ModelInfoTTL = time.Hour
+	ModelTagsTTL = 30 * time.Minute
+)
+
+// 模型仓库标签信息的缓存地址前缀，缓存内容为 registry.TagInfo 的 JSON
+const registryCachePrefix = "registry:"
+
+// Page 缓存的页面内容及用于重新验证的信息
+type Page struct {
+	Url          string
+	Body         []byte
+	ETag         string
+	LastModified string
+	TTL          time.Duration
+	FetchedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+func (p *Page) expired() bool {
+	return !time.Now().Before(p.ExpiresAt)
+}
+
+func (p *Page) touch() {
+	p.FetchedAt = time.Now()
+	p.ExpiresAt = p.FetchedAt.Add(p.TTL)
+}
+
+// Cache 页面缓存，未命中时返回 nil
+type Cache interface {
+	Get(ctx context.Context, url string) (*Page, error)
+	Put(ctx context.Context, page *Page) error
+}
@@ -3,6 +3,7 @@ package ollama
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
@@ -11,16 +12,91 @@ import (
 	"net/url"
 	"ollama-desktop/internal/config"
 	"ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/format"
+	"ollama-desktop/internal/ollama/registry"
+	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var noModelError = errors.New("No Model")
 
+// 页面中显示的结果总数，如 "152 models"
+var resultCountPattern = regexp.MustCompile(`(?i)^([\d,]+)\s+(?:models?|results?)$`)
+
+// 页面类型
+const (
+	pageSearch  = "search"
+	pageLibrary = "library"
+	pageModel   = "model"
+	pageTags    = "tags"
+)
+
+// 页面解析使用的选择器，ollama.com 页面结构变化时需要同步调整
+const (
+	selectorModelList     = "ul.grid"
+	selectorModelItem     = "ul.grid > li > a"
+	selectorItemName      = "div.flex > h2"
+	selectorItemSummary   = "div.flex > p.break-words"
+	selectorSearchTags    = "div.flex > div.space-x-2 > span"
+	selectorSearchInfos   = "div.flex > p.space-x-5 > span"
+	selectorLibraryTags   = "div.space-y-2 > div.space-x-2 > span"
+	selectorLibraryInfos  = "div.space-y-2 > p.space-x-5 > span"
+	selectorModelName     = "main > div.flex > div.flex > div.mb-3"
+	selectorModelSummary  = "main > div.flex #summary"
+	selectorModelTags     = "main > div.flex #summary+div > div.space-x-2 > span"
+	selectorModelInfos    = "main > div.flex #summary+div > p.space-x-5 > span"
+	selectorModelTagsLink = "main > div.flex section a[x-test-tags-link]"
+	selectorTagRow        = "section > div > div > div.px-4.py-3 > div"
+	selectorTagName       = "div.space-x-2 a.group"
+	selectorTagInfo       = "div.space-x-1 > span"
+	selectorFileMeta      = "#file-explorer > section.py-2 > div a"
+	selectorReadme        = "div#textareaInput > textarea#editor"
+	selectorPageLink      = "[hx-get*='page='], a[href*='page=']"
+	selectorResultCount   = "main *"
+)
+
+// ParseError 页面解析失败，通常意味着 ollama.com 页面结构发生了变化
+type ParseError struct {
+	Page     string `json:"page"`
+	Selector string `json:"selector"`
+	Message  string `json:"message"`
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse %s page: %s (%s)", e.Page, e.Message, e.Selector)
+}
+
 type Client struct {
 	Base *url.URL
 	Http *http.Client
+	// Registry 不为空时通过模型仓库获取标签的精确信息
+	Registry *registry.Client
+	// Cache 不为空时缓存页面，过期后通过 ETag/Last-Modified 重新验证
+	Cache Cache
+	// OnStale 无法访问 ollama.com 而使用过期缓存时回调
+	OnStale func(page *Page, err error)
+	// OnDegraded 页面解析不完整时回调
+	OnDegraded func(err *ParseError)
+	// OnParsed 页面完整解析时回调，用于恢复解析状态
+	OnParsed func(page string)
+}
+
+func (c *Client) degraded(err *ParseError) {
+	if c.OnDegraded != nil {
+		c.OnDegraded(err)
+	}
+}
+
+func (c *Client) parsed(page string) {
+	if c.OnParsed != nil {
+		c.OnParsed(page)
+	}
 }
 
 func checkError(resp *http.Response, body []byte) error {
@@ -31,24 +107,81 @@ func checkError(resp *http.Response, body []byte) error {
 	return ollama.StatusError{StatusCode: resp.StatusCode, Status: resp.Status, ErrorMessage: string(body)}
 }
 
-func (c *Client) do(ctx context.Context, path string, reqData map[string]string) ([]byte, error) {
+// 获取页面，返回的 bool 表示是否使用了过期的缓存
+func (c *Client) do(ctx context.Context, path string, query url.Values, ttl time.Duration) (*Page, bool, error) {
 	requestURL := c.Base.JoinPath(path)
-	if len(reqData) > 0 {
-		rawQuery := ""
-		for name, value := range reqData {
-			if rawQuery != "" {
-				rawQuery += "&"
-			}
-			rawQuery += name + "=" + url.QueryEscape(value)
+	if len(query) > 0 {
+		requestURL.RawQuery = query.Encode()
+	}
+	rawUrl := requestURL.String()
+	if c.Cache == nil {
+		page, err := c.fetch(ctx, &Page{Url: rawUrl, TTL: ttl}, false)
+		return page, false, err
+	}
+
+	page, _ := c.Cache.Get(ctx, rawUrl)
+	if page != nil && !page.expired() {
+		return page, false, nil
+	}
+	if page == nil {
+		page = &Page{Url: rawUrl}
+	}
+	page.TTL = ttl
+	fresh, err := c.fetch(ctx, page, true)
+	if err != nil {
+		if len(page.Body) > 0 && staleAllowed(err) {
+			c.stale(page, err)
+			return page, true, nil
+		}
+		return nil, false, err
+	}
+	return fresh, false, nil
+}
+
+// 网络异常或服务端异常时使用过期的缓存
+func staleAllowed(err error) bool {
+	var statusError ollama.StatusError
+	if errors.As(err, &statusError) {
+		return statusError.StatusCode >= http.StatusInternalServerError
+	}
+	return !errors.Is(err, registry.ErrNotFound)
+}
+
+func (c *Client) stale(page *Page, err error) {
+	if c.OnStale != nil {
+		c.OnStale(page, err)
+	}
+}
+
+// Refresh 重新验证缓存的页面，模型仓库的标签信息重新获取
+func (c *Client) Refresh(ctx context.Context, page *Page) error {
+	if key, ok := strings.CutPrefix(page.Url, registryCachePrefix); ok {
+		if c.Registry == nil || c.Cache == nil {
+			return nil
 		}
-		requestURL.RawQuery = rawQuery
+		_, err := c.refreshTag(ctx, page, registry.ParseName(key))
+		return err
 	}
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	_, err := c.fetch(ctx, page, true)
+	return err
+}
+
+// 请求页面，存在缓存时发送条件请求，未修改时仅延长缓存有效期
+func (c *Client) fetch(ctx context.Context, page *Page, cache bool) (*Page, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, page.Url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	request.Header.Set("User-Agent", fmt.Sprintf("ollama-desktop/%s (%s %s) Go/%s", config.BuildVersion, runtime.GOARCH, runtime.GOOS, runtime.Version()))
+	if len(page.Body) > 0 {
+		if page.ETag != "" {
+			request.Header.Set("If-None-Match", page.ETag)
+		}
+		if page.LastModified != "" {
+			request.Header.Set("If-Modified-Since", page.LastModified)
+		}
+	}
 
 	respObj, err := c.Http.Do(request)
 	if err != nil {
@@ -61,10 +194,21 @@ func (c *Client) do(ctx context.Context, path string, reqData map[string]string)
 		return nil, err
 	}
 
-	if err := checkError(respObj, respBody); err != nil {
-		return nil, err
+	if respObj.StatusCode == http.StatusNotModified && len(page.Body) > 0 {
+		page.touch()
+	} else {
+		if err := checkError(respObj, respBody); err != nil {
+			return nil, err
+		}
+		page.Body = respBody
+		page.ETag = respObj.Header.Get("ETag")
+		page.LastModified = respObj.Header.Get("Last-Modified")
+		page.touch()
 	}
-	return respBody, nil
+	if cache && c.Cache != nil {
+		_ = c.Cache.Put(ctx, page)
+	}
+	return page, nil
 }
 
 // 解析模型名称、是否归档
@@ -113,64 +257,121 @@ func parseTags(spans *goquery.Selection) []string {
 	return tags
 }
 
-func (c *Client) Search(ctx context.Context, request *ollama.SearchRequest) ([]*ollama.ModelInfo, error) {
-	respBody, err := c.do(ctx, "/search", map[string]string{
-		"q": request.Q,
-		"o": request.O,
-		"c": request.C,
-	})
-	if err != nil {
-		return nil, err
-	}
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(respBody))
-	if err != nil {
-		return nil, err
+func (c *Client) Search(ctx context.Context, request *ollama.SearchRequest) (*ollama.SearchResponse, error) {
+	query := url.Values{}
+	query.Set("q", request.Q)
+	query.Set("o", request.O)
+	for _, category := range request.C {
+		query.Add("c", category)
 	}
-	var list []*ollama.ModelInfo
-
-	// 解析模型信息
-	doc.Find("ul.grid > li > a").Each(func(_ int, item *goquery.Selection) {
-		name, archive := parseNameArchive(item.Find("div.flex > h2").First())
-		description := strings.TrimSpace(item.Find("div.flex > p.break-words").First().Text())
-		tags := parseTags(item.Find("div.flex > div.space-x-2 > span"))
-		pullCount, tagCount, updated := parsePullTagCountAndUpdated(item.Find("div.flex > p.space-x-5 > span"))
-		list = append(list, &ollama.ModelInfo{
-			Name:        name,
-			Archive:     archive,
-			Description: description,
-			Tags:        tags,
-			PullCount:   pullCount,
-			TagCount:    tagCount,
-			UpdateTime:  updated,
-		})
-	})
-
-	return list, err
+	// 指定了查询条件的搜索可能没有结果
+	filtered := request.Q != "" || len(request.C) > 0
+	return c.modelList(ctx, "/search", query, request.Page, SearchTTL, pageSearch, selectorSearchTags, selectorSearchInfos, filtered)
 }
 
-func (c *Client) Library(ctx context.Context, request *ollama.LibraryRequest) ([]*ollama.ModelInfo, error) {
+func (c *Client) Library(ctx context.Context, request *ollama.LibraryRequest) (*ollama.SearchResponse, error) {
 	if request.Sort == "" {
 		request.Sort = "featured"
 	}
-	respBody, err := c.do(ctx, "/library", map[string]string{
-		"q":    request.Q,
-		"sort": request.Sort,
-	})
+	query := url.Values{}
+	query.Set("q", request.Q)
+	query.Set("sort", request.Sort)
+	return c.modelList(ctx, "/library", query, request.Page, LibraryTTL, pageLibrary, selectorLibraryTags, selectorLibraryInfos, request.Q != "")
+}
+
+// 分页获取模型列表，第一页不携带页码以复用原有缓存
+func (c *Client) modelList(ctx context.Context, path string, query url.Values, page int, ttl time.Duration,
+	pageName, tagsSelector, infosSelector string, filtered bool) (*ollama.SearchResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	cached, stale, err := c.do(ctx, path, query, ttl)
 	if err != nil {
 		return nil, err
 	}
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(respBody))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(cached.Body))
 	if err != nil {
 		return nil, err
 	}
-	var list []*ollama.ModelInfo
+	models, err := c.parseModelList(doc, pageName, tagsSelector, infosSelector, filtered || page > 1)
+	if err != nil {
+		return nil, err
+	}
+	response := &ollama.SearchResponse{
+		Models:    models,
+		Page:      page,
+		HasMore:   len(models) > 0 && hasPage(doc, page+1),
+		Total:     resultCount(doc),
+		Stale:     stale,
+		FetchedAt: cached.FetchedAt,
+	}
+	// 页面未显示总数时，只有一页的结果数即为总数
+	if response.Total == 0 && page == 1 && !response.HasMore {
+		response.Total = len(models)
+	}
+	return response, nil
+}
+
+// 解析页面中显示的结果总数，未显示时返回 0
+func resultCount(doc *goquery.Document) int {
+	total := 0
+	doc.Find(selectorResultCount).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		match := resultCountPattern.FindStringSubmatch(text)
+		if match == nil {
+			return true
+		}
+		total, _ = strconv.Atoi(strings.ReplaceAll(match[1], ",", ""))
+		return false
+	})
+	return total
+}
 
-	// 解析模型信息
-	doc.Find("ul.grid > li > a").Each(func(_ int, item *goquery.Selection) {
-		name, archive := parseNameArchive(item.Find("div.flex > h2").First())
-		description := strings.TrimSpace(item.Find("div.flex > p.break-words").First().Text())
-		tags := parseTags(item.Find("div.space-y-2 > div.space-x-2 > span"))
-		pullCount, tagCount, updated := parsePullTagCountAndUpdated(item.Find("div.space-y-2 > p.space-x-5 > span"))
+// 页面中存在指向指定页码的链接或 htmx 请求时，认为存在该页
+func hasPage(doc *goquery.Document, page int) bool {
+	expected := strconv.Itoa(page)
+	found := false
+	doc.Find(selectorPageLink).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, attr := range []string{"hx-get", "href"} {
+			link, ok := s.Attr(attr)
+			if !ok {
+				continue
+			}
+			u, err := url.Parse(link)
+			if err == nil && u.Query().Get("page") == expected {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// 解析模型列表，名称缺失的模型将被跳过，allowEmpty 时列表存在但没有模型视为无结果
+func (c *Client) parseModelList(doc *goquery.Document, page, tagsSelector, infosSelector string, allowEmpty bool) ([]*ollama.ModelInfo, error) {
+	var list []*ollama.ModelInfo
+	items := doc.Find(selectorModelItem)
+	if items.Size() == 0 {
+		if allowEmpty && doc.Find(selectorModelList).Size() > 0 {
+			c.parsed(page)
+			return nil, nil
+		}
+		// 未过滤的列表至少存在一个模型，解析不到说明页面结构发生了变化
+		c.degraded(&ParseError{Page: page, Selector: selectorModelItem, Message: "no models found"})
+		return nil, nil
+	}
+	items.Each(func(_ int, item *goquery.Selection) {
+		name, archive := parseNameArchive(item.Find(selectorItemName).First())
+		if name == "" {
+			return
+		}
+		description := strings.TrimSpace(item.Find(selectorItemSummary).First().Text())
+		tags := parseTags(item.Find(tagsSelector))
+		pullCount, tagCount, updated := parsePullTagCountAndUpdated(item.Find(infosSelector))
 		list = append(list, &ollama.ModelInfo{
 			Name:        name,
 			Archive:     archive,
@@ -182,7 +383,38 @@ func (c *Client) Library(ctx context.Context, request *ollama.LibraryRequest) ([
 		})
 	})
 
-	return list, err
+	if skipped := items.Size() - len(list); skipped > 0 {
+		err := &ParseError{Page: page, Selector: selectorItemName, Message: fmt.Sprintf("%d of %d models without name", skipped, items.Size())}
+		c.degraded(err)
+		if len(list) == 0 {
+			return nil, err
+		}
+		return list, nil
+	}
+	c.parsed(page)
+	return list, nil
+}
+
+// 解析模型详情、标签页面的头部信息
+func (c *Client) parseModelHeader(doc *goquery.Document, page string) (*ollama.ModelInfo, error) {
+	name, archive := parseNameArchive(doc.Find(selectorModelName).First())
+	if name == "" {
+		err := &ParseError{Page: page, Selector: selectorModelName, Message: "model name not found"}
+		c.degraded(err)
+		return nil, err
+	}
+	description := strings.TrimSpace(doc.Find(selectorModelSummary).First().Text())
+	tags := parseTags(doc.Find(selectorModelTags))
+	pullCount, tagCount, updated := parsePullTagCountAndUpdated(doc.Find(selectorModelInfos))
+	return &ollama.ModelInfo{
+		Name:        name,
+		Archive:     archive,
+		Description: description,
+		Tags:        tags,
+		PullCount:   pullCount,
+		TagCount:    tagCount,
+		UpdateTime:  updated,
+	}, nil
 }
 
 func (c *Client) ModelTags(ctx context.Context, model string) (*ollama.ModelTagsResponse, error) {
@@ -192,49 +424,40 @@ func (c *Client) ModelTags(ctx context.Context, model string) (*ollama.ModelTags
 	} else {
 		path = fmt.Sprintf("/library/%s/tags", model)
 	}
-	respBody, err := c.do(ctx, path, nil)
+	cached, stale, err := c.do(ctx, path, nil, ModelTagsTTL)
 	if err != nil {
 		return nil, err
 	}
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(respBody))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(cached.Body))
 	if err != nil {
 		return nil, err
 	}
 
-	name, archive := parseNameArchive(doc.Find("main > div.flex > div.flex > div.mb-3").First())
-	description := strings.TrimSpace(doc.Find("main > div.flex #summary").First().Text())
-	tags := parseTags(doc.Find("main > div.flex #summary+div > div.space-x-2 > span"))
-	pullCount, tagCount, updated := parsePullTagCountAndUpdated(doc.Find("main > div.flex #summary+div > p.space-x-5 > span"))
-	modelInfo := &ollama.ModelInfo{
-		Name:        name,
-		Archive:     archive,
-		Description: description,
-		Tags:        tags,
-		PullCount:   pullCount,
-		TagCount:    tagCount,
-		UpdateTime:  updated,
+	modelInfo, err := c.parseModelHeader(doc, pageTags)
+	if err != nil {
+		return nil, err
 	}
 
 	response := &ollama.ModelTagsResponse{
-		Model: modelInfo,
+		Model:     modelInfo,
+		Stale:     stale,
+		FetchedAt: cached.FetchedAt,
 	}
 
-	tagsNode := doc.Find("section > div > div > div.px-4.py-3 > div")
-
-	if tagsNode.Size() < 1 {
-		modelInfo.TagCount = 0
-		return response, nil
-	}
+	tagsNode := doc.Find(selectorTagRow)
 
 	var modelTags []*ollama.ModelTag
 	for i := 0; i < tagsNode.Size(); i++ {
 		tagNode := tagsNode.Eq(i)
 
-		line1Node := tagNode.Find("div.space-x-2").First()
-		name := strings.TrimSpace(line1Node.Find("a.group").Text())
+		name := strings.TrimSpace(tagNode.Find(selectorTagName).First().Text())
 		latest := strings.Contains(name, "latest")
 
-		infos := strings.Split(tagNode.Find("div.space-x-1 > span").Text(), "•")
+		// 格式：id • size • updated
+		infos := strings.Split(tagNode.Find(selectorTagInfo).Text(), "•")
+		if name == "" || len(infos) < 3 {
+			continue
+		}
 		tag := &ollama.ModelTag{
 			Name:       name,
 			Latest:     latest,
@@ -244,10 +467,143 @@ func (c *Client) ModelTags(ctx context.Context, model string) (*ollama.ModelTags
 		}
 		modelTags = append(modelTags, tag)
 	}
-	response.Tags = modelTags
-	modelInfo.TagCount = len(modelTags)
+	// 模型至少存在一个标签，解析不到标签说明页面结构发生了变化
+	if tagsNode.Size() == 0 {
+		c.degraded(&ParseError{Page: pageTags, Selector: selectorTagRow, Message: "no tags found"})
+	} else if skipped := tagsNode.Size() - len(modelTags); skipped > 0 {
+		c.degraded(&ParseError{Page: pageTags, Selector: selectorTagInfo, Message: fmt.Sprintf("%d of %d tags unparsable", skipped, tagsNode.Size())})
+	} else {
+		c.parsed(pageTags)
+	}
+	tags, failed, registryStale, err := c.registryTags(ctx, model, modelTags)
+	response.Tags, response.FailedTags = tags, failed
+	response.Stale = response.Stale || registryStale
+	if err != nil {
+		response.RegistryError = err.Error()
+	}
+	modelInfo.TagCount = len(response.Tags)
 
-	return response, err
+	return response, nil
+}
+
+// 通过模型仓库补充标签的摘要、大小及量化信息，页面仅用于获取描述信息，
+// 返回获取失败的标签，以及是否使用了过期的缓存
+func (c *Client) registryTags(ctx context.Context, model string, tags []*ollama.ModelTag) ([]*ollama.ModelTag, []string, bool, error) {
+	if c.Registry == nil {
+		return tags, nil, false, nil
+	}
+	fallback := len(tags) == 0
+	if fallback {
+		// 页面解析不到标签时，至少返回 latest 标签
+		tags = []*ollama.ModelTag{{Name: model + ":latest", Latest: true}}
+	}
+	sem := make(chan struct{}, 6)
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var failed []string
+	var lastErr error
+	var stale int32
+	for _, tag := range tags {
+		wg.Add(1)
+		go func(tag *ollama.ModelTag) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			name := registry.ParseName(model)
+			name.Tag = tagName(tag.Name)
+			info, tagStale, err := c.tagInfo(ctx, name)
+			if tagStale {
+				atomic.StoreInt32(&stale, 1)
+			}
+			if err != nil {
+				lock.Lock()
+				failed = append(failed, tag.Name)
+				lastErr = err
+				lock.Unlock()
+				return
+			}
+			tag.Digest = info.Digest
+			tag.SizeBytes = info.Size
+			tag.Size = format.HumanBytes(info.Size)
+			if id := strings.TrimPrefix(info.Digest, "sha256:"); tag.Id == "" && len(id) >= 12 {
+				tag.Id = id[:12]
+			}
+			if info.Config != nil {
+				tag.Format = info.Config.ModelFormat
+				tag.Family = info.Config.ModelFamily
+				tag.ParameterSize = info.Config.ModelType
+				tag.Quantization = info.Config.FileType
+			}
+		}(tag)
+	}
+	wg.Wait()
+	sort.Strings(failed)
+	if fallback && len(failed) > 0 {
+		return nil, failed, stale == 1, lastErr
+	}
+	return tags, failed, stale == 1, lastErr
+}
+
+// 获取标签的精确信息，按 model:tag 缓存，无法访问模型仓库时使用过期的缓存
+func (c *Client) tagInfo(ctx context.Context, name registry.Name) (*registry.TagInfo, bool, error) {
+	if c.Cache == nil {
+		info, err := c.Registry.Tag(ctx, name)
+		return info, false, err
+	}
+	key := registryCachePrefix + name.String()
+	page, _ := c.Cache.Get(ctx, key)
+	var cached *registry.TagInfo
+	if page != nil {
+		var info registry.TagInfo
+		if err := json.Unmarshal(page.Body, &info); err == nil {
+			cached = &info
+		}
+	}
+	if cached != nil && !page.expired() {
+		return cached, false, nil
+	}
+	if page == nil {
+		page = &Page{Url: key}
+	}
+	info, err := c.refreshTag(ctx, page, name)
+	if err != nil {
+		if cached != nil && staleAllowed(err) {
+			c.stale(page, err)
+			return cached, true, nil
+		}
+		return nil, false, err
+	}
+	return info, false, nil
+}
+
+// 从模型仓库重新获取标签信息并写入缓存
+func (c *Client) refreshTag(ctx context.Context, page *Page, name registry.Name) (*registry.TagInfo, error) {
+	info, err := c.Registry.Tag(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	page.Body, page.ETag, page.LastModified = body, "", ""
+	page.TTL = ModelTagsTTL
+	page.touch()
+	_ = c.Cache.Put(ctx, page)
+	return info, nil
+}
+
+// 从页面中的标签名称中提取标签，qwen2:7b => 7b
+func tagName(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return "latest"
+	}
+	name = fields[0]
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
 }
 
 func (c *Client) ModelInfo(ctx context.Context, modelTag string) (*ollama.ModelInfoResponse, error) {
@@ -257,40 +613,32 @@ func (c *Client) ModelInfo(ctx context.Context, modelTag string) (*ollama.ModelI
 	} else {
 		path = fmt.Sprintf("/library/%s", modelTag)
 	}
-	respBody, err := c.do(ctx, path, nil)
+	cached, stale, err := c.do(ctx, path, nil, ModelInfoTTL)
 	if err != nil {
 		return nil, err
 	}
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(respBody))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(cached.Body))
 	if err != nil {
 		return nil, err
 	}
 
-	name, archive := parseNameArchive(doc.Find("main > div.flex > div.flex > div.mb-3").First())
-	description := strings.TrimSpace(doc.Find("main > div.flex #summary").First().Text())
-	tags := parseTags(doc.Find("main > div.flex #summary+div > div.space-x-2 > span"))
-	pullCount, tagCount, updated := parsePullTagCountAndUpdated(doc.Find("main > div.flex #summary+div > p.space-x-5 > span"))
-	if tagCount == 0 {
+	modelInfo, err := c.parseModelHeader(doc, pageModel)
+	if err != nil {
+		return nil, err
+	}
+	c.parsed(pageModel)
+	if modelInfo.TagCount == 0 {
 		// 模型详情页面排版变化，此处需要重新获取标签数量
-		tagCountStr := strings.TrimSpace(doc.Find("main > div.flex section a[x-test-tags-link]").First().Text())
+		tagCountStr := strings.TrimSpace(doc.Find(selectorModelTagsLink).First().Text())
 		if strings.HasSuffix(tagCountStr, "Tags") {
-			tagCount, _ = strconv.Atoi(strings.TrimSpace(tagCountStr[:len(tagCountStr)-4]))
-		} else if strings.HasSuffix(tagCountStr, "Tags") {
-			tagCount, _ = strconv.Atoi(strings.TrimSpace(tagCountStr[:len(tagCountStr)-3]))
+			modelInfo.TagCount, _ = strconv.Atoi(strings.TrimSpace(tagCountStr[:len(tagCountStr)-4]))
+		} else if strings.HasSuffix(tagCountStr, "Tag") {
+			modelInfo.TagCount, _ = strconv.Atoi(strings.TrimSpace(tagCountStr[:len(tagCountStr)-3]))
 		}
 	}
-	modelInfo := &ollama.ModelInfo{
-		Name:        name,
-		Archive:     archive,
-		Description: description,
-		Tags:        tags,
-		PullCount:   pullCount,
-		TagCount:    tagCount,
-		UpdateTime:  updated,
-	}
 
 	var modelMetas []*ollama.ModelMeta
-	doc.Find("#file-explorer > section.py-2 > div a").Each(func(i int, selection *goquery.Selection) {
+	doc.Find(selectorFileMeta).Each(func(i int, selection *goquery.Selection) {
 		children := selection.Children()
 		name := strings.TrimSpace(children.Eq(0).Text())
 		var content string
@@ -317,13 +665,15 @@ func (c *Client) ModelInfo(ctx context.Context, modelTag string) (*ollama.ModelI
 		modelMetas = append(modelMetas, meta)
 	})
 
-	readme := doc.Find("div#textareaInput > textarea#editor").Eq(0).Text()
+	readme := doc.Find(selectorReadme).Eq(0).Text()
 
 	response := &ollama.ModelInfoResponse{
 		Model: modelInfo,
 		//Tags:   modelTags,
-		Metas:  modelMetas,
-		Readme: readme,
+		Metas:     modelMetas,
+		Readme:    readme,
+		Stale:     stale,
+		FetchedAt: cached.FetchedAt,
 	}
-	return response, err
+	return response, nil
 }
@@ -0,0 +1,445 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/registry"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 按请求路径返回 testdata 中的页面
+//
+// testdata 中的页面是按 ollama.com 页面结构手工精简的，并非原始页面的录制，
+// 页面结构是否变化由 TestClient_Online 对照线上页面检查
+func newTestClient(t *testing.T, pages map[string]string) (*Client, *[]*ParseError) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	base, _ := url.Parse(server.URL)
+	var degraded []*ParseError
+	client := &Client{
+		Base: base,
+		Http: server.Client(),
+		OnDegraded: func(err *ParseError) {
+			degraded = append(degraded, err)
+		},
+	}
+	return client, &degraded
+}
+
+func TestClient_Search(t *testing.T) {
+	testCases := []struct {
+		name     string
+		page     string
+		q        string
+		expected []*ollama.ModelInfo
+		hasMore  bool
+		total    int
+		degraded bool
+	}{
+		{
+			name: "normal",
+			page: "search.html",
+			q:    "qwen",
+			expected: []*ollama.ModelInfo{
+				{Name: "qwen2.5", Tags: []string{"tools", "0.5b", "7b"}, PullCount: "5.4M", TagCount: 133, UpdateTime: "3 months ago"},
+				{Name: "falcon", Archive: true, Tags: []string{"7b"}, PullCount: "1", TagCount: 1, UpdateTime: "1 year ago"},
+			},
+			hasMore: true,
+			total:   1152,
+		},
+		{
+			name:     "changed layout",
+			page:     "tags.html",
+			q:        "qwen",
+			degraded: true,
+		},
+		{
+			name: "no results",
+			page: "search_empty.html",
+			q:    "nothing",
+		},
+		{
+			name:     "empty without query",
+			page:     "search_empty.html",
+			degraded: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, degraded := newTestClient(t, map[string]string{"/search": tc.page})
+			var parsed []string
+			client.OnParsed = func(page string) { parsed = append(parsed, page) }
+			resp, err := client.Search(context.Background(), &ollama.SearchRequest{Q: tc.q})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Page != 1 || resp.HasMore != tc.hasMore {
+				t.Errorf("expected page 1 has more %v, got page %d has more %v", tc.hasMore, resp.Page, resp.HasMore)
+			}
+			if resp.Total != tc.total {
+				t.Errorf("expected total %d, got %d", tc.total, resp.Total)
+			}
+			list := resp.Models
+			if tc.degraded != (len(*degraded) > 0) {
+				t.Errorf("expected degraded %v, got %v", tc.degraded, *degraded)
+			}
+			// 完整解析时通知恢复解析状态
+			if tc.degraded != (len(parsed) == 0) {
+				t.Errorf("expected parsed %v, got %v", !tc.degraded, parsed)
+			}
+			if len(list) != len(tc.expected) {
+				t.Fatalf("expected %d models, got %d", len(tc.expected), len(list))
+			}
+			for i, expected := range tc.expected {
+				actual := list[i]
+				if actual.Name != expected.Name || actual.Archive != expected.Archive || actual.PullCount != expected.PullCount ||
+					actual.TagCount != expected.TagCount || actual.UpdateTime != expected.UpdateTime || len(actual.Tags) != len(expected.Tags) {
+					t.Errorf("expected %+v, got %+v", expected, actual)
+				}
+				if actual.Description == "" {
+					t.Errorf("model %s without description", actual.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_SearchPage(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		body, err := os.ReadFile(filepath.Join("testdata", "library.html"))
+		if err != nil {
+			t.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL)
+	client := &Client{Base: base, Http: server.Client()}
+	resp, err := client.Search(context.Background(), &ollama.SearchRequest{Q: "qwen", C: []string{"vision", "tools"}, Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("page") != "2" || len(query["c"]) != 2 || query["c"][0] != "vision" || query["c"][1] != "tools" {
+		t.Errorf("unexpected query %v", query)
+	}
+	if resp.Page != 2 || resp.HasMore {
+		t.Errorf("unexpected response page %d has more %v", resp.Page, resp.HasMore)
+	}
+}
+
+func TestClient_Library(t *testing.T) {
+	client, degraded := newTestClient(t, map[string]string{"/library": "library.html"})
+	resp, err := client.Library(context.Background(), &ollama.LibraryRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := resp.Models
+	if resp.HasMore {
+		t.Error("unexpected more pages")
+	}
+	// 页面未显示总数时，只有一页的结果数即为总数
+	if resp.Total != 1 {
+		t.Errorf("expected total 1, got %d", resp.Total)
+	}
+	if len(*degraded) > 0 {
+		t.Errorf("unexpected degraded %v", (*degraded)[0])
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 model, got %d", len(list))
+	}
+	model := list[0]
+	if model.Name != "llama3.1" || model.PullCount != "24.1M" || model.TagCount != 93 || len(model.Tags) != 4 {
+		t.Errorf("unexpected model %+v", model)
+	}
+}
+
+func TestClient_ModelInfo(t *testing.T) {
+	client, _ := newTestClient(t, map[string]string{"/library/qwen2": "model.html"})
+	response, err := client.ModelInfo(context.Background(), "qwen2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Model.Name != "qwen2" || response.Model.TagCount != 97 {
+		t.Errorf("unexpected model %+v", response.Model)
+	}
+	if len(response.Metas) != 2 || response.Metas[0].Content != "arch qwen2 · parameters 7.62B · quantization Q4_0" {
+		t.Errorf("unexpected metas %+v", response.Metas)
+	}
+	if response.Readme == "" {
+		t.Error("readme not found")
+	}
+
+	// 页面结构变化时返回解析错误
+	client, degraded := newTestClient(t, map[string]string{"/library/qwen2": "search.html"})
+	_, err = client.ModelInfo(context.Background(), "qwen2")
+	var parseError *ParseError
+	if !errors.As(err, &parseError) || parseError.Page != pageModel {
+		t.Errorf("expected parse error, got %v", err)
+	}
+	if len(*degraded) != 1 {
+		t.Errorf("expected 1 degraded, got %d", len(*degraded))
+	}
+}
+
+func TestClient_ModelTags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		page     string
+		tags     []string
+		degraded bool
+	}{
+		{"normal", "tags.html", []string{"latest", "0.5b"}, false},
+		{"malformed row", "tags_degraded.html", []string{"latest"}, true},
+		{"no tags", "model.html", nil, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, degraded := newTestClient(t, map[string]string{"/library/qwen2/tags": tc.page})
+			response, err := client.ModelTags(context.Background(), "qwen2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Tags) != len(tc.tags) {
+				t.Fatalf("expected %d tags, got %d", len(tc.tags), len(response.Tags))
+			}
+			for i, name := range tc.tags {
+				if response.Tags[i].Name != name || response.Tags[i].Id == "" || response.Tags[i].Size == "" {
+					t.Errorf("unexpected tag %+v", response.Tags[i])
+				}
+			}
+			if response.Model.TagCount != len(tc.tags) {
+				t.Errorf("expected tag count %d, got %d", len(tc.tags), response.Model.TagCount)
+			}
+			if tc.degraded != (len(*degraded) > 0) {
+				t.Errorf("expected degraded %v, got %v", tc.degraded, *degraded)
+			}
+		})
+	}
+}
+
+// 将模型仓库的请求转发到测试服务
+type rewriteTransport struct {
+	host string
+}
+
+func (t rewriteTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.URL.Scheme, request.URL.Host = "http", t.host
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+func TestClient_ModelTagsRegistry(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "tags.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"schemaVersion":2,"config":{"digest":"sha256:c","size":1},"layers":[{"digest":"sha256:m","size":99}]}`
+	var lock sync.Mutex
+	manifests := 0
+	down := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/library/qwen2/tags":
+			w.Write(body)
+		case "/v2/library/qwen2/manifests/latest":
+			lock.Lock()
+			manifests++
+			lock.Unlock()
+			if down {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Write([]byte(manifest))
+		case "/v2/library/qwen2/blobs/sha256:c":
+			w.Write([]byte(`{"model_family":"qwen2","model_type":"7.6B","file_type":"Q4_0"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL)
+	httpClient := &http.Client{Transport: rewriteTransport{host: base.Host}}
+	cache := &memoryCache{pages: map[string]*Page{}}
+	client := &Client{
+		Base:     base,
+		Http:     httpClient,
+		Registry: &registry.Client{Http: httpClient},
+		Cache:    cache,
+	}
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			// 模型仓库不可用时使用过期的标签信息
+			for _, page := range cache.pages {
+				page.ExpiresAt = page.FetchedAt
+			}
+			down = true
+		}
+		response, err := client.ModelTags(context.Background(), "qwen2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Tags) != 2 {
+			t.Fatalf("expected 2 tags, got %d", len(response.Tags))
+		}
+		latest := response.Tags[0]
+		if latest.SizeBytes != 100 || latest.Quantization != "Q4_0" || latest.Digest == "" {
+			t.Errorf("unexpected latest tag %+v", latest)
+		}
+		// 0.5b 获取失败时保留页面中的信息
+		if len(response.FailedTags) != 1 || response.FailedTags[0] != response.Tags[1].Name || response.RegistryError == "" {
+			t.Errorf("expected failed tag %s, got %v %q", response.Tags[1].Name, response.FailedTags, response.RegistryError)
+		}
+		if response.Tags[1].Size == "" || response.Tags[1].Digest != "" {
+			t.Errorf("unexpected failed tag %+v", response.Tags[1])
+		}
+		if response.Stale != (i == 2) {
+			t.Errorf("expected stale %v, got %v", i == 2, response.Stale)
+		}
+	}
+	if manifests != 2 {
+		t.Errorf("expected cached registry lookup, got %d manifest requests", manifests)
+	}
+}
+
+type memoryCache struct {
+	mu    sync.Mutex
+	pages map[string]*Page
+}
+
+func (m *memoryCache) Get(_ context.Context, url string) (*Page, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pages[url], nil
+}
+
+func (m *memoryCache) Put(_ context.Context, page *Page) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pages[page.Url] = page
+	return nil
+}
+
+func TestClient_Cache(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "library.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, notModified := 0, 0
+	down := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if down {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL)
+	cache := &memoryCache{pages: map[string]*Page{}}
+	stale := 0
+	client := &Client{Base: base, Http: server.Client(), Cache: cache, OnStale: func(*Page, error) { stale++ }}
+
+	library := func() *ollama.SearchResponse {
+		resp, err := client.Library(context.Background(), &ollama.LibraryRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Models) != 1 {
+			t.Fatalf("expected 1 model, got %d", len(resp.Models))
+		}
+		return resp
+	}
+	expire := func() {
+		for _, page := range cache.pages {
+			page.ExpiresAt = page.FetchedAt
+		}
+	}
+
+	if resp := library(); resp.Stale || resp.FetchedAt.IsZero() {
+		t.Errorf("unexpected cache info stale %v fetched at %v", resp.Stale, resp.FetchedAt)
+	}
+	library()
+	if requests != 1 {
+		t.Errorf("expected fresh cache hit, got %d requests", requests)
+	}
+
+	expire()
+	library()
+	if requests != 2 || notModified != 1 {
+		t.Errorf("expected revalidation, got %d requests, %d not modified", requests, notModified)
+	}
+
+	expire()
+	down = true
+	if resp := library(); stale != 1 || !resp.Stale {
+		t.Errorf("expected stale fallback, got %d %v", stale, resp.Stale)
+	}
+}
+
+// 对照 ollama.com 的线上页面检查选择器，需要网络，设置 OLLAMA_ONLINE_TEST=1 时执行
+func TestClient_Online(t *testing.T) {
+	if os.Getenv("OLLAMA_ONLINE_TEST") == "" {
+		t.Skip("set OLLAMA_ONLINE_TEST=1 to check the selectors against ollama.com")
+	}
+	base, _ := url.Parse("https://ollama.com")
+	var degraded []*ParseError
+	client := &Client{
+		Base: base,
+		Http: &http.Client{Timeout: 30 * time.Second},
+		OnDegraded: func(err *ParseError) {
+			degraded = append(degraded, err)
+		},
+	}
+	ctx := context.Background()
+	if resp, err := client.Search(ctx, &ollama.SearchRequest{Q: "qwen"}); err != nil || len(resp.Models) == 0 {
+		t.Errorf("search: %v", err)
+	}
+	if resp, err := client.Library(ctx, &ollama.LibraryRequest{}); err != nil || len(resp.Models) == 0 {
+		t.Errorf("library: %v", err)
+	}
+	if _, err := client.ModelInfo(ctx, "qwen2.5"); err != nil {
+		t.Errorf("model info: %v", err)
+	}
+	if resp, err := client.ModelTags(ctx, "qwen2.5"); err != nil || len(resp.Tags) == 0 {
+		t.Errorf("model tags: %v", err)
+	}
+	for _, err := range degraded {
+		t.Errorf("degraded: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"ollama-desktop/internal/config"
+	"ollama-desktop/internal/ollama"
+	"ollama-desktop/internal/ollama/storage"
+	"runtime"
+	"strings"
+)
+
+const manifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+var ErrNotFound = errors.New("manifest not found")
+
+// Name 模型在仓库中的完整名称
+type Name struct {
+	Host      string `json:"host"`
+	Namespace string `json:"namespace"`
+	Model     string `json:"model"`
+	Tag       string `json:"tag"`
+}
+
+// ParseName 解析模型名称，缺省部分使用 registry.ollama.ai/library/<model>:latest
+func ParseName(name string) Name {
+	n := Name{Host: storage.DefaultRegistry, Namespace: storage.DefaultNamespace, Tag: "latest"}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		n.Tag = name[i+1:]
+		name = name[:i]
+	}
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		n.Model = parts[0]
+	case 2:
+		n.Namespace, n.Model = parts[0], parts[1]
+	default:
+		n.Host = parts[0]
+		n.Namespace = strings.Join(parts[1:len(parts)-1], "/")
+		n.Model = parts[len(parts)-1]
+	}
+	return n
+}
+
+func (n Name) String() string {
+	return storage.ModelName(n.Host, n.Namespace, n.Model, n.Tag)
+}
+
+// Client 访问 OCI 风格的模型仓库接口
+type Client struct {
+	// Scheme 为空时使用 https
+	Scheme string
+	Http   *http.Client
+}
+
+func (c *Client) url(name Name, path string) string {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	u := &url.URL{Scheme: scheme, Host: name.Host}
+	return u.JoinPath("v2", name.Namespace, name.Model, path).String()
+}
+
+func (c *Client) get(ctx context.Context, rawUrl, accept string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	request.Header.Set("User-Agent", fmt.Sprintf("ollama-desktop/%s (%s %s) Go/%s", config.BuildVersion, runtime.GOARCH, runtime.GOOS, runtime.Version()))
+
+	respObj, err := c.Http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer respObj.Body.Close()
+
+	respBody, err := io.ReadAll(respObj.Body)
+	if err != nil {
+		return nil, err
+	}
+	if respObj.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if respObj.StatusCode >= http.StatusBadRequest {
+		return nil, ollama.StatusError{StatusCode: respObj.StatusCode, Status: respObj.Status, ErrorMessage: string(respBody)}
+	}
+	return respBody, nil
+}
+
+// Manifest 获取模型清单及其摘要，摘要与 ollama list 返回的一致
+func (c *Client) Manifest(ctx context.Context, name Name) (*storage.Manifest, string, error) {
+	body, err := c.get(ctx, c.url(name, "manifests/"+name.Tag), manifestMediaType)
+	if err != nil {
+		return nil, "", err
+	}
+	var manifest storage.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, "", err
+	}
+	return &manifest, fmt.Sprintf("sha256:%x", sha256.Sum256(body)), nil
+}
+
+// Blob 获取仓库中的数据文件内容，仅适用于较小的文件，如模型配置
+func (c *Client) Blob(ctx context.Context, name Name, digest string) ([]byte, error) {
+	return c.get(ctx, c.url(name, "blobs/"+digest), "")
+}
+
+// ModelConfig 模型配置，对应清单中 config 指向的数据文件
+type ModelConfig struct {
+	ModelFormat   string   `json:"model_format"`
+	ModelFamily   string   `json:"model_family"`
+	ModelFamilies []string `json:"model_families"`
+	// 参数规模，如 7.6B
+	ModelType string `json:"model_type"`
+	// 量化类型，如 Q4_K_M
+	FileType string `json:"file_type"`
+}
+
+// TagInfo 标签的精确信息
+type TagInfo struct {
+	Name   Name            `json:"name"`
+	Digest string          `json:"digest"`
+	Size   int64           `json:"size"`
+	Layers []storage.Layer `json:"layers"`
+	Config *ModelConfig    `json:"config,omitempty"`
+}
+
+// Tag 获取标签的摘要、各层大小及量化信息
+func (c *Client) Tag(ctx context.Context, name Name) (*TagInfo, error) {
+	manifest, digest, err := c.Manifest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	info := &TagInfo{
+		Name:   name,
+		Digest: digest,
+		Size:   manifest.Config.Size,
+		Layers: manifest.Layers,
+	}
+	for _, layer := range manifest.Layers {
+		info.Size += layer.Size
+	}
+	if manifest.Config.Digest != "" {
+		body, err := c.Blob(ctx, name, manifest.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+		var config ModelConfig
+		if err := json.Unmarshal(body, &config); err != nil {
+			return nil, err
+		}
+		info.Config = &config
+	}
+	return info, nil
+}
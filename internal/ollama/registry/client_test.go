@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Name
+	}{
+		{"qwen2", Name{"registry.ollama.ai", "library", "qwen2", "latest"}},
+		{"qwen2:7b", Name{"registry.ollama.ai", "library", "qwen2", "7b"}},
+		{"jack/llama:q4_K_M", Name{"registry.ollama.ai", "jack", "llama", "q4_K_M"}},
+		{"localhost:5000/team/llama:v1", Name{"localhost:5000", "team", "llama", "v1"}},
+		{"hf.co/org/repo/model", Name{"hf.co", "org/repo", "model", "latest"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if name := ParseName(tc.input); name != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestClient_Manifest(t *testing.T) {
+	body := `{"schemaVersion":2,"config":{"digest":"sha256:c","size":1},"layers":[{"mediaType":"application/vnd.ollama.image.model","digest":"sha256:m","size":100}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/library/qwen2/manifests/7b" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Accept") != manifestMediaType {
+			t.Errorf("unexpected accept %q", r.Header.Get("Accept"))
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL)
+	client := &Client{Scheme: "http", Http: server.Client()}
+	name := Name{Host: base.Host, Namespace: "library", Model: "qwen2", Tag: "7b"}
+	manifest, digest, err := client.Manifest(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(body))) {
+		t.Errorf("unexpected digest %s", digest)
+	}
+	if len(manifest.Layers) != 1 || manifest.Layers[0].Size != 100 {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+
+	name.Tag = "missing"
+	if _, _, err := client.Manifest(context.Background(), name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package serverlog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// 从文件末尾向前读取时每次读取的大小
+const chunkSize = 64 * 1024
+
+// TailFile 读取文件最后 n 行
+func TailFile(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size()
+	var data []byte
+	for offset > 0 && bytes.Count(data, []byte{'\n'}) <= n {
+		size := int64(chunkSize)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := file.ReadAt(chunk, offset); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		data = append(chunk, data...)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	// 未读到文件开头时第一行可能不完整
+	if offset > 0 && len(lines) > 0 {
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+	return lines, nil
+}
+
+// FileFollower 持续读取文件新增的内容，文件被截断或轮转时从头读取
+type FileFollower struct {
+	path    string
+	offset  int64
+	partial string
+}
+
+// NewFileFollower 从文件当前末尾开始读取
+func NewFileFollower(path string) *FileFollower {
+	f := &FileFollower{path: path}
+	if info, err := os.Stat(path); err == nil {
+		f.offset = info.Size()
+	}
+	return f
+}
+
+// Read 读取上次读取后新增的完整行，文件不存在时返回空
+func (f *FileFollower) Read() ([]string, error) {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < f.offset {
+		f.offset, f.partial = 0, ""
+	}
+	if info.Size() == f.offset {
+		return nil, nil
+	}
+	data := make([]byte, info.Size()-f.offset)
+	n, err := file.ReadAt(data, f.offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	f.offset += int64(n)
+	content := f.partial + string(data[:n])
+	end := strings.LastIndexByte(content, '\n')
+	if end < 0 {
+		f.partial = content
+		return nil, nil
+	}
+	f.partial = content[end+1:]
+	return strings.Split(content[:end], "\n"), nil
+}
+
+// JournalCursorPrefix journalctl --show-cursor 输出的游标行前缀
+const JournalCursorPrefix = "-- cursor: "
+
+// ParseJournal 解析 journalctl -o cat --show-cursor 的输出，返回日志行及游标
+func ParseJournal(output string) ([]string, string) {
+	var lines []string
+	cursor := ""
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		if strings.HasPrefix(line, JournalCursorPrefix) {
+			cursor = strings.TrimPrefix(line, JournalCursorPrefix)
+			continue
+		}
+		// 没有新日志时的提示
+		if line == "-- No entries --" || line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, cursor
+}
@@ -0,0 +1,109 @@
+// Package serverlog Ollama 服务日志的解析、过滤及读取
+package serverlog
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	LevelDebug = "DEBUG"
+	LevelInfo  = "INFO"
+	LevelWarn  = "WARN"
+	LevelError = "ERROR"
+)
+
+var levelOrders = map[string]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2, LevelError: 3}
+
+// Line 解析后的日志行
+type Line struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Raw     string `json:"raw"`
+}
+
+var (
+	// slog 文本格式，如 time=2024-07-01T10:00:00.000+08:00 level=INFO source=images.go:781 msg="total blobs: 5"
+	slogPattern = regexp.MustCompile(`^time=(\S+) level=(\w+)(?: source=\S+)? msg=("(?:[^"\\]|\\.)*"|\S+)(.*)$`)
+	// gin 访问日志，如 [GIN] 2024/07/01 - 10:00:00 | 200 | 1.2ms | 127.0.0.1 | HEAD "/"
+	ginPattern = regexp.MustCompile(`^\[GIN\] (\d{4}/\d{2}/\d{2} - \d{2}:\d{2}:\d{2}) \| (.*)$`)
+)
+
+// Parse 解析一行日志，无法识别的格式视为 INFO，错误输出视为 ERROR
+func Parse(raw string) *Line {
+	line := &Line{Level: LevelInfo, Message: raw, Raw: raw}
+	if m := slogPattern.FindStringSubmatch(raw); m != nil {
+		line.Time = m[1]
+		line.Level = normalizeLevel(m[2])
+		line.Message = m[3]
+		if msg, err := strconv.Unquote(m[3]); err == nil {
+			line.Message = msg
+		}
+		line.Message += m[4]
+		return line
+	}
+	if m := ginPattern.FindStringSubmatch(raw); m != nil {
+		line.Time = m[1]
+		line.Message = m[2]
+		// 访问日志的状态码为 5xx 时视为错误
+		if status := strings.TrimSpace(strings.SplitN(m[2], "|", 2)[0]); strings.HasPrefix(status, "5") {
+			line.Level = LevelError
+		}
+		return line
+	}
+	lower := strings.ToLower(raw)
+	if strings.HasPrefix(lower, "error:") || strings.HasPrefix(lower, "panic:") || strings.HasPrefix(lower, "fatal") {
+		line.Level = LevelError
+	}
+	return line
+}
+
+func normalizeLevel(level string) string {
+	level = strings.ToUpper(level)
+	switch {
+	case strings.HasPrefix(level, "DEBUG"), level == "TRACE":
+		return LevelDebug
+	case strings.HasPrefix(level, "WARN"):
+		return LevelWarn
+	case strings.HasPrefix(level, "ERR"):
+		return LevelError
+	}
+	return LevelInfo
+}
+
+// Filter 按最低级别及关键字过滤日志
+type Filter struct {
+	// Level 最低日志级别，为空时不过滤
+	Level string `json:"level"`
+	// Query 忽略大小写的关键字
+	Query string `json:"query"`
+}
+
+func (f *Filter) Match(line *Line) bool {
+	if f.Level != "" && levelOrders[line.Level] < levelOrders[normalizeLevel(f.Level)] {
+		return false
+	}
+	if f.Query != "" && !strings.Contains(strings.ToLower(line.Raw), strings.ToLower(f.Query)) {
+		return false
+	}
+	return true
+}
+
+// Apply 解析并过滤日志，最多保留最后 limit 行，limit 小于等于 0 时不限制
+func (f *Filter) Apply(raws []string, limit int) []*Line {
+	lines := make([]*Line, 0, len(raws))
+	for _, raw := range raws {
+		if raw == "" {
+			continue
+		}
+		if line := Parse(raw); f.Match(line) {
+			lines = append(lines, line)
+		}
+	}
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines
+}
@@ -0,0 +1,133 @@
+package serverlog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		raw     string
+		time    string
+		level   string
+		message string
+	}{
+		{`time=2024-07-01T10:00:00.000+08:00 level=INFO source=images.go:781 msg="total blobs: 5"`,
+			"2024-07-01T10:00:00.000+08:00", LevelInfo, "total blobs: 5"},
+		{`time=2024-07-01T10:00:01.000+08:00 level=ERROR source=sched.go:456 msg="error loading llama server" error="llama runner process has terminated: signal: killed"`,
+			"2024-07-01T10:00:01.000+08:00", LevelError, `error loading llama server error="llama runner process has terminated: signal: killed"`},
+		{`time=2024-07-01T10:00:02.000+08:00 level=WARN msg=ignored`, "2024-07-01T10:00:02.000+08:00", LevelWarn, "ignored"},
+		{`[GIN] 2024/07/01 - 10:00:03 | 200 |    1.2ms |       127.0.0.1 | HEAD     "/"`, "2024/07/01 - 10:00:03", LevelInfo,
+			`200 |    1.2ms |       127.0.0.1 | HEAD     "/"`},
+		{`[GIN] 2024/07/01 - 10:00:04 | 500 |    3.1s |       127.0.0.1 | POST     "/api/chat"`, "2024/07/01 - 10:00:04", LevelError,
+			`500 |    3.1s |       127.0.0.1 | POST     "/api/chat"`},
+		{`Error: listen tcp 127.0.0.1:11434: bind: address already in use`, "", LevelError,
+			`Error: listen tcp 127.0.0.1:11434: bind: address already in use`},
+		{`llama_model_loader: loaded meta data with 21 key-value pairs`, "", LevelInfo, `llama_model_loader: loaded meta data with 21 key-value pairs`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.raw, func(t *testing.T) {
+			line := Parse(tc.raw)
+			if line.Time != tc.time || line.Level != tc.level || line.Message != tc.message {
+				t.Errorf("unexpected line %+v", line)
+			}
+		})
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	raws := []string{
+		`time=2024-07-01T10:00:00.000+08:00 level=DEBUG msg="load model"`,
+		`time=2024-07-01T10:00:01.000+08:00 level=INFO msg="Load Model qwen2"`,
+		`time=2024-07-01T10:00:02.000+08:00 level=WARN msg="low vram"`,
+		`Error: model not found`,
+		"",
+	}
+	testCases := []struct {
+		filter   Filter
+		limit    int
+		expected []string
+	}{
+		{Filter{}, 0, raws[:4]},
+		{Filter{Level: "warn"}, 0, raws[2:4]},
+		{Filter{Query: "LOAD model"}, 0, raws[:2]},
+		{Filter{Level: LevelInfo, Query: "model"}, 1, raws[3:4]},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			var actual []string
+			for _, line := range tc.filter.Apply(raws, tc.limit) {
+				actual = append(actual, line.Raw)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	var lines []string
+	for i := 0; i < 20000; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{1, 100, 20000, 30000} {
+		actual, err := TailFile(path, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := lines
+		if n < len(lines) {
+			expected = lines[len(lines)-n:]
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("tail %d: got %d lines, first %q", n, len(actual), actual[0])
+		}
+	}
+}
+
+func TestFileFollower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	follower := NewFileFollower(path)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	_, _ = file.WriteString("one\ntw")
+	if lines, _ := follower.Read(); !reflect.DeepEqual(lines, []string{"one"}) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	_, _ = file.WriteString("o\n")
+	if lines, _ := follower.Read(); !reflect.DeepEqual(lines, []string{"two"}) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	// 日志轮转后从头读取
+	if err := os.WriteFile(path, []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := follower.Read(); !reflect.DeepEqual(lines, []string{"new"}) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
+
+func TestParseJournal(t *testing.T) {
+	lines, cursor := ParseJournal("time=x level=INFO msg=a\nError: b\n-- cursor: s=abc;i=1\n")
+	if !reflect.DeepEqual(lines, []string{"time=x level=INFO msg=a", "Error: b"}) || cursor != "s=abc;i=1" {
+		t.Errorf("unexpected lines %q, cursor %q", lines, cursor)
+	}
+	if lines, cursor = ParseJournal("-- No entries --\n"); len(lines) != 0 || cursor != "" {
+		t.Errorf("unexpected lines %q, cursor %q", lines, cursor)
+	}
+}
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultRegistry  = "registry.ollama.ai"
+	DefaultNamespace = "library"
+)
+
+// Layer 模型清单中的层
+type Layer struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Manifest 模型清单
+type Manifest struct {
+	SchemaVersion int     `json:"schemaVersion"`
+	MediaType     string  `json:"mediaType"`
+	Config        Layer   `json:"config"`
+	Layers        []Layer `json:"layers"`
+}
+
+// Blob 模型数据文件
+type Blob struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	// 引用该文件的模型
+	Models []string `json:"models,omitempty"`
+}
+
+// ModelUsage 单个模型的磁盘占用
+type ModelUsage struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+	// 模型引用的全部文件大小
+	TotalSize int64 `json:"totalSize"`
+	// 仅被该模型引用的文件大小，即删除该模型实际释放的空间
+	UniqueSize int64 `json:"uniqueSize"`
+	// 与其他模型共享的文件大小
+	SharedSize int64 `json:"sharedSize"`
+	// 清单中存在但磁盘上缺失的文件
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Usage 模型目录的磁盘占用分析结果
+type Usage struct {
+	Dir    string        `json:"dir"`
+	Models []*ModelUsage `json:"models"`
+	Blobs  []*Blob       `json:"blobs"`
+	// 未被任何模型引用的文件，包括未完成的下载
+	Orphans    []*Blob `json:"orphans"`
+	TotalSize  int64   `json:"totalSize"`
+	OrphanSize int64   `json:"orphanSize"`
+}
+
+// Scan 分析模型目录中的清单与数据文件
+func Scan(dir string) (*Usage, error) {
+	blobs, err := scanBlobs(filepath.Join(dir, "blobs"))
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := scanManifests(filepath.Join(dir, "manifests"))
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{Dir: dir}
+	var names []string
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// 统计每个文件被引用的模型，同一模型多次引用同一文件时只计一次
+	for _, name := range names {
+		for _, digest := range manifests[name].digests() {
+			if blob, ok := blobs[digest]; ok {
+				blob.Models = append(blob.Models, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		manifest := manifests[name]
+		model := &ModelUsage{Name: name, Digest: manifest.digest}
+		for _, digest := range manifest.digests() {
+			blob, ok := blobs[digest]
+			if !ok {
+				model.Missing = append(model.Missing, digest)
+				continue
+			}
+			model.TotalSize += blob.Size
+			if len(blob.Models) == 1 {
+				model.UniqueSize += blob.Size
+			} else {
+				model.SharedSize += blob.Size
+			}
+		}
+		usage.Models = append(usage.Models, model)
+	}
+
+	var digests []string
+	for digest := range blobs {
+		digests = append(digests, digest)
+	}
+	sort.Strings(digests)
+	for _, digest := range digests {
+		blob := blobs[digest]
+		usage.TotalSize += blob.Size
+		usage.Blobs = append(usage.Blobs, blob)
+		if len(blob.Models) == 0 {
+			usage.Orphans = append(usage.Orphans, blob)
+			usage.OrphanSize += blob.Size
+		}
+	}
+	return usage, nil
+}
+
+// 磁盘上的数据文件，以摘要为键
+func scanBlobs(dir string) (map[string]*Blob, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*Blob{}, nil
+		}
+		return nil, err
+	}
+	blobs := make(map[string]*Blob, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		// 文件名格式为 sha256-<hex>，旧版本为 sha256:<hex>
+		digest := strings.Replace(entry.Name(), "-", ":", 1)
+		blobs[digest] = &Blob{Digest: digest, Size: info.Size()}
+	}
+	return blobs, nil
+}
+
+type manifestFile struct {
+	Manifest
+	digest string
+}
+
+// 清单引用的全部文件摘要，已去重
+func (m *manifestFile) digests() []string {
+	seen := make(map[string]bool)
+	var digests []string
+	for _, layer := range append([]Layer{m.Config}, m.Layers...) {
+		if layer.Digest == "" || seen[layer.Digest] {
+			continue
+		}
+		seen[layer.Digest] = true
+		digests = append(digests, layer.Digest)
+	}
+	return digests
+}
+
+// 磁盘上的模型清单，以模型名称为键
+func scanManifests(dir string) (map[string]*manifestFile, error) {
+	manifests := make(map[string]*manifestFile)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		// <host>/<namespace>/<model>/<tag>
+		if len(parts) != 4 {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		manifest := &manifestFile{digest: fmt.Sprintf("sha256:%x", sha256.Sum256(data))}
+		if err := json.Unmarshal(data, &manifest.Manifest); err != nil {
+			return fmt.Errorf("invalid manifest %s: %w", rel, err)
+		}
+		manifests[ModelName(parts[0], parts[1], parts[2], parts[3])] = manifest
+		return nil
+	})
+	return manifests, err
+}
+
+// ModelName 返回与 ollama list 一致的模型名称
+func ModelName(host, namespace, model, tag string) string {
+	switch {
+	case host == DefaultRegistry && namespace == DefaultNamespace:
+		return fmt.Sprintf("%s:%s", model, tag)
+	case host == DefaultRegistry:
+		return fmt.Sprintf("%s/%s:%s", namespace, model, tag)
+	default:
+		return fmt.Sprintf("%s/%s/%s:%s", host, namespace, model, tag)
+	}
+}
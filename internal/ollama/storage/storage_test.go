@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBlob(t *testing.T, dir, digest string, size int) {
+	name := filepath.Join(dir, "blobs", "sha256-"+digest)
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeManifest(t *testing.T, dir, path string, config string, layers ...string) {
+	manifest := Manifest{SchemaVersion: 2, Config: Layer{Digest: "sha256:" + config}}
+	for _, layer := range layers {
+		manifest.Layers = append(manifest.Layers, Layer{Digest: "sha256:" + layer})
+	}
+	data, _ := json.Marshal(manifest)
+	name := filepath.Join(dir, "manifests", filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	writeBlob(t, dir, "weights", 1000)
+	writeBlob(t, dir, "template", 10)
+	writeBlob(t, dir, "config1", 1)
+	writeBlob(t, dir, "config2", 2)
+	writeBlob(t, dir, "system", 5)
+	writeBlob(t, dir, "orphan", 300)
+	writeManifest(t, dir, "registry.ollama.ai/library/qwen2/7b", "config1", "weights", "template")
+	writeManifest(t, dir, "registry.ollama.ai/jack/qwen2-chat/latest", "config2", "weights", "template", "system")
+	writeManifest(t, dir, "example.com/team/broken/v1", "config1", "missing")
+
+	usage, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.TotalSize != 1318 || usage.OrphanSize != 300 || len(usage.Orphans) != 1 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+
+	models := make(map[string]*ModelUsage)
+	for _, model := range usage.Models {
+		models[model.Name] = model
+	}
+	testCases := []struct {
+		name    string
+		total   int64
+		unique  int64
+		shared  int64
+		missing int
+	}{
+		{"qwen2:7b", 1011, 0, 1011, 0},
+		{"jack/qwen2-chat:latest", 1017, 7, 1010, 0},
+		{"example.com/team/broken:v1", 1, 0, 1, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, ok := models[tc.name]
+			if !ok {
+				t.Fatalf("model %s not found", tc.name)
+			}
+			if model.TotalSize != tc.total || model.UniqueSize != tc.unique ||
+				model.SharedSize != tc.shared || len(model.Missing) != tc.missing {
+				t.Errorf("unexpected model usage %+v", model)
+			}
+		})
+	}
+}
+
+func TestScanEmpty(t *testing.T) {
+	usage, err := Scan(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.Models) != 0 || usage.TotalSize != 0 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+}
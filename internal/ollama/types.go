@@ -339,7 +339,9 @@ type ShowResponse struct {
 	Messages      []Message      `json:"messages,omitempty"`
 	ModelInfo     map[string]any `json:"model_info,omitempty"`
 	ProjectorInfo map[string]any `json:"projector_info,omitempty"`
-	ModifiedAt    time.Time      `json:"modified_at,omitempty"`
+	// Capabilities is only reported by newer servers, e.g. completion, vision, tools.
+	Capabilities []string  `json:"capabilities,omitempty"`
+	ModifiedAt   time.Time `json:"modified_at,omitempty"`
 }
 
 // CopyRequest is the request passed to [Client.Copy].
@@ -736,7 +738,21 @@ type SearchRequest struct {
 	//embedding
 	//vision
 	//tools
-	C string `json:"c"`
+	//thinking
+	C []string `json:"c"`
+	// 页码，从 1 开始
+	Page int `json:"page"`
+}
+
+type SearchResponse struct {
+	Models  []*ModelInfo `json:"models"`
+	Page    int          `json:"page"`
+	HasMore bool         `json:"hasMore"`
+	// Total 页面中显示的结果总数，0 表示未知
+	Total int `json:"total"`
+	// Stale 无法访问 ollama.com 时使用了过期的缓存，FetchedAt 为页面的获取时间
+	Stale     bool      `json:"stale"`
+	FetchedAt time.Time `json:"fetchedAt"`
 }
 
 type ModelInfo struct {
@@ -755,11 +771,19 @@ type LibraryRequest struct {
 	// popular
 	// newest
 	Sort string `json:"sort"`
+	// 页码，从 1 开始
+	Page int `json:"page"`
 }
 
 type ModelTagsResponse struct {
 	Model *ModelInfo  `json:"model"`
 	Tags  []*ModelTag `json:"tags"`
+	// 从模型仓库获取信息失败的标签，这些标签仅包含页面中的信息
+	FailedTags    []string `json:"failedTags,omitempty"`
+	RegistryError string   `json:"registryError,omitempty"`
+	// Stale 页面或模型仓库信息使用了过期的缓存，FetchedAt 为页面的获取时间
+	Stale     bool      `json:"stale"`
+	FetchedAt time.Time `json:"fetchedAt"`
 }
 
 type ModelTag struct {
@@ -768,12 +792,23 @@ type ModelTag struct {
 	Id         string `json:"id"`
 	Size       string `json:"size"`
 	UpdateTime string `json:"updateTime"`
+
+	// 以下字段来自模型仓库
+	Digest        string `json:"digest,omitempty"`
+	SizeBytes     int64  `json:"sizeBytes,omitempty"`
+	Format        string `json:"format,omitempty"`
+	Family        string `json:"family,omitempty"`
+	ParameterSize string `json:"parameterSize,omitempty"`
+	Quantization  string `json:"quantization,omitempty"`
 }
 
 type ModelInfoResponse struct {
 	Model  *ModelInfo   `json:"model"`
 	Metas  []*ModelMeta `json:"metas"`
 	Readme string       `json:"readme"`
+	// Stale 无法访问 ollama.com 时使用了过期的缓存，FetchedAt 为页面的获取时间
+	Stale     bool      `json:"stale"`
+	FetchedAt time.Time `json:"fetchedAt"`
 }
 
 type ModelMeta struct {
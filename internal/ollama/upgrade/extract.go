@@ -0,0 +1,180 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTarGz 解压 tar.gz 到目录，拒绝写到目录之外的条目
+func ExtractTarGz(archive, prefix string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := safeJoin(prefix, header.Name)
+		if err != nil {
+			return err
+		}
+		mode := header.FileInfo().Mode()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			_, err = mkdirWithin(prefix, target)
+		case tar.TypeReg:
+			err = writeFile(prefix, target, reader, mode.Perm())
+		case tar.TypeSymlink:
+			err = symlink(prefix, target, header.Linkname)
+		case tar.TypeLink:
+			var source string
+			if source, err = safeJoin(prefix, header.Linkname); err == nil {
+				if _, err = mkdirWithin(prefix, filepath.Dir(target)); err == nil {
+					os.Remove(target)
+					err = os.Link(source, target)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ExtractZip 解压 zip 到目录，保留应用包中的符号链接
+func ExtractZip(archive, prefix string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for _, entry := range reader.File {
+		target, err := safeJoin(prefix, entry.Name)
+		if err != nil {
+			return err
+		}
+		mode := entry.Mode()
+		if mode.IsDir() {
+			if _, err := mkdirWithin(prefix, target); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		if mode&os.ModeSymlink != 0 {
+			var link []byte
+			if link, err = io.ReadAll(rc); err == nil {
+				err = symlink(prefix, target, string(link))
+			}
+		} else {
+			err = writeFile(prefix, target, rc, mode.Perm())
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 拼接条目路径，条目为绝对路径或包含 .. 跳出目录时返回错误
+func safeJoin(prefix, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	target := filepath.Join(prefix, name)
+	rel, err := filepath.Rel(prefix, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return target, nil
+}
+
+// 创建目录，并确认解析符号链接后仍位于安装目录之内
+func mkdirWithin(prefix, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	realPrefix, err := filepath.EvalSymlinks(prefix)
+	if err != nil {
+		return "", err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	if _, err := safeJoin(realPrefix, relPath(realPrefix, realDir)); err != nil {
+		return "", fmt.Errorf("illegal path in archive: %s", dir)
+	}
+	return realDir, nil
+}
+
+func relPath(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return rel
+}
+
+func writeFile(prefix, target string, reader io.Reader, perm os.FileMode) error {
+	if _, err := mkdirWithin(prefix, filepath.Dir(target)); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0o644
+	}
+	// 先删除旧文件，避免覆盖正在运行的可执行文件
+	os.Remove(target)
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// 创建符号链接，链接目标必须位于目录之内
+func symlink(prefix, target, link string) error {
+	if filepath.IsAbs(link) {
+		return fmt.Errorf("illegal symlink in archive: %s -> %s", target, link)
+	}
+	parent, err := mkdirWithin(prefix, filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	realPrefix, err := filepath.EvalSymlinks(prefix)
+	if err != nil {
+		return err
+	}
+	if _, err := safeJoin(realPrefix, relPath(realPrefix, filepath.Join(parent, link))); err != nil {
+		return fmt.Errorf("illegal symlink in archive: %s -> %s", target, link)
+	}
+	os.Remove(target)
+	return os.Symlink(link, target)
+}
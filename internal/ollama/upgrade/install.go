@@ -0,0 +1,114 @@
+package upgrade
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// InstallTarGz 先解压到安装目录下的临时目录，成功后再替换 bin/ollama、lib/ollama 等条目，
+// 解压失败时不影响已安装的版本
+func InstallTarGz(archive, prefix string) error {
+	return install(archive, prefix, ExtractTarGz, 2)
+}
+
+// InstallZip 先解压到安装目录下的临时目录，成功后再替换 Ollama.app 等顶层条目
+func InstallZip(archive, prefix string) error {
+	return install(archive, prefix, ExtractZip, 1)
+}
+
+// 临时目录与安装目录位于同一文件系统，保证替换时只需重命名
+func install(archive, prefix string, extract func(archive, prefix string) error, depth int) error {
+	if err := os.MkdirAll(prefix, 0o755); err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(prefix, ".ollama-upgrade-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	target := filepath.Join(staging, "new")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		return err
+	}
+	if err := extract(archive, target); err != nil {
+		return err
+	}
+	entries, err := entriesAt(target, depth)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("empty release archive")
+	}
+	return replace(target, prefix, filepath.Join(staging, "old"), entries)
+}
+
+// 列出指定深度的条目，如深度为 2 时返回 bin/ollama、lib/ollama
+func entriesAt(root string, depth int) ([]string, error) {
+	entries := []string{""}
+	for i := 0; i < depth; i++ {
+		var next []string
+		for _, entry := range entries {
+			items, err := os.ReadDir(filepath.Join(root, entry))
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				// 忽略深度不足的文件
+				if i < depth-1 && !item.IsDir() {
+					continue
+				}
+				next = append(next, filepath.Join(entry, item.Name()))
+			}
+		}
+		entries = next
+	}
+	return entries, nil
+}
+
+// 依次将旧条目移到备份目录并移入新条目，失败时恢复已替换的条目
+func replace(source, prefix, backup string, entries []string) error {
+	type replaced struct {
+		entry string
+		old   bool
+	}
+	var done []replaced
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			target := filepath.Join(prefix, done[i].entry)
+			os.RemoveAll(target)
+			if done[i].old {
+				os.Rename(filepath.Join(backup, done[i].entry), target)
+			}
+		}
+	}
+	for _, entry := range entries {
+		target := filepath.Join(prefix, entry)
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			rollback()
+			return err
+		}
+		item := replaced{entry: entry}
+		if _, err := os.Lstat(target); err == nil {
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(backup, entry)), 0o755); err != nil {
+				rollback()
+				return err
+			}
+			if err := os.Rename(target, filepath.Join(backup, entry)); err != nil {
+				rollback()
+				return err
+			}
+			item.old = true
+		}
+		if err := os.Rename(filepath.Join(source, entry), target); err != nil {
+			if item.old {
+				os.Rename(filepath.Join(backup, entry), target)
+			}
+			rollback()
+			return err
+		}
+		done = append(done, item)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package upgrade 选择、下载、校验并解压 Ollama 发行包
+package upgrade
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"ollama-desktop/internal/util"
+	"os"
+	"strings"
+)
+
+// ChecksumAsset 发布附带的校验文件，格式与 sha256sum 输出相同
+const ChecksumAsset = "sha256sum.txt"
+
+const (
+	// MethodArchive 解压到指定的安装目录
+	MethodArchive = "archive"
+	// MethodInstaller 交给系统安装程序
+	MethodInstaller = "installer"
+)
+
+var ErrChecksumMismatch = errors.New("sha256 checksum mismatch")
+
+// SelectAsset 选择与平台及架构匹配的发行文件，返回文件及安装方式
+func SelectAsset(item *util.Item, goos, goarch string) (*util.Asset, string, error) {
+	var names []string
+	method := MethodArchive
+	switch goos {
+	case "linux":
+		names = []string{fmt.Sprintf("ollama-linux-%s.tgz", goarch)}
+	case "darwin":
+		names = []string{"Ollama-darwin.zip"}
+	case "windows":
+		names = []string{"OllamaSetup.exe"}
+		method = MethodInstaller
+	default:
+		return nil, "", fmt.Errorf("unsupported operating system: %s", goos)
+	}
+	for _, name := range names {
+		if asset := item.Asset(name); asset != nil {
+			return asset, method, nil
+		}
+	}
+	return nil, "", fmt.Errorf("no release asset for %s/%s in %s", goos, goarch, item.Name)
+}
+
+// ParseChecksums 解析 sha256sum 格式的校验文件，返回文件名到摘要的映射
+func ParseChecksums(content string) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// 文件名可能带有 ./ 前缀或表示二进制模式的 *
+		name := strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./")
+		checksums[name] = strings.ToLower(fields[0])
+	}
+	return checksums
+}
+
+// Checksum 下载校验文件并返回指定文件的摘要
+func Checksum(ctx context.Context, client *http.Client, item *util.Item, name string) (string, error) {
+	asset := item.Asset(ChecksumAsset)
+	if asset == nil {
+		return "", fmt.Errorf("no %s in %s", ChecksumAsset, item.Name)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.Url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download %s: %s", ChecksumAsset, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	checksum, ok := ParseChecksums(string(body))[name]
+	if !ok {
+		return "", fmt.Errorf("no checksum for %s in %s", name, ChecksumAsset)
+	}
+	return checksum, nil
+}
+
+// Download 下载文件并校验 sha256，校验通过后才写入 path，progress 报告已下载及总字节数
+func Download(ctx context.Context, client *http.Client, url, path, checksum string, progress func(completed, total int64)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	part := path + ".part"
+	file, err := os.Create(part)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	writer := &progressWriter{total: resp.ContentLength, fn: progress}
+	_, err = io.Copy(io.MultiWriter(file, hash, writer), resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(part)
+		return err
+	}
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, checksum) {
+		os.Remove(part)
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, checksum, actual)
+	}
+	return os.Rename(part, path)
+}
+
+type progressWriter struct {
+	completed int64
+	total     int64
+	fn        func(completed, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.completed += int64(len(p))
+	if w.fn != nil {
+		w.fn(w.completed, w.total)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,241 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"ollama-desktop/internal/util"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSelectAsset(t *testing.T) {
+	item := &util.Item{Name: "v0.3.12", Assets: []*util.Asset{
+		{Name: "ollama-linux-amd64.tgz"}, {Name: "ollama-linux-arm64.tgz"},
+		{Name: "Ollama-darwin.zip"}, {Name: "OllamaSetup.exe"}, {Name: ChecksumAsset},
+	}}
+	testCases := []struct {
+		goos, goarch string
+		name, method string
+	}{
+		{"linux", "amd64", "ollama-linux-amd64.tgz", MethodArchive},
+		{"linux", "arm64", "ollama-linux-arm64.tgz", MethodArchive},
+		{"darwin", "arm64", "Ollama-darwin.zip", MethodArchive},
+		{"windows", "amd64", "OllamaSetup.exe", MethodInstaller},
+	}
+	for _, tc := range testCases {
+		asset, method, err := SelectAsset(item, tc.goos, tc.goarch)
+		if err != nil || asset.Name != tc.name || method != tc.method {
+			t.Errorf("%s/%s: unexpected asset %+v, method %s, error %v", tc.goos, tc.goarch, asset, method, err)
+		}
+	}
+	if _, _, err := SelectAsset(item, "linux", "riscv64"); err == nil {
+		t.Error("expected error for missing asset")
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	checksums := ParseChecksums("ABC123  ./ollama-linux-amd64.tgz\ndef456 *OllamaSetup.exe\n\ninvalid\n")
+	expected := map[string]string{"ollama-linux-amd64.tgz": "abc123", "OllamaSetup.exe": "def456"}
+	if !reflect.DeepEqual(checksums, expected) {
+		t.Errorf("expected %v, got %v", expected, checksums)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("ollama"), 10000)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + ChecksumAsset:
+			_, _ = w.Write([]byte(checksum + "  ./ollama-linux-amd64.tgz\n"))
+		default:
+			_, _ = w.Write(content)
+		}
+	}))
+	defer server.Close()
+	item := &util.Item{Name: "v0.3.12", Assets: []*util.Asset{{Name: ChecksumAsset, Url: server.URL + "/" + ChecksumAsset}}}
+	actual, err := Checksum(context.Background(), server.Client(), item, "ollama-linux-amd64.tgz")
+	if err != nil || actual != checksum {
+		t.Fatalf("unexpected checksum %s, error %v", actual, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ollama.tgz")
+	var completed int64
+	err = Download(context.Background(), server.Client(), server.URL+"/ollama.tgz", path, checksum, func(c, _ int64) {
+		completed = c
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, content) || completed != int64(len(content)) {
+		t.Errorf("unexpected download, completed %d", completed)
+	}
+
+	path = filepath.Join(t.TempDir(), "ollama.tgz")
+	err = Download(context.Background(), server.Client(), server.URL+"/ollama.tgz", path, "00", nil)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected no file after checksum mismatch")
+	}
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Error("expected partial file removed")
+	}
+}
+
+type entry struct {
+	name, body, link string
+	mode             int64
+	dir              bool
+}
+
+func writeTarGz(t *testing.T, entries []entry) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: e.mode, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			header.Typeflag, header.Size = tar.TypeDir, 0
+		case e.link != "":
+			header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			_, _ = tw.Write([]byte(e.body))
+		}
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+	path := filepath.Join(t.TempDir(), "archive.tgz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractTarGz(t *testing.T) {
+	archive := writeTarGz(t, []entry{
+		{name: "./bin/", dir: true, mode: 0o755},
+		{name: "./bin/ollama", body: "binary", mode: 0o755},
+		{name: "./lib/ollama/libggml.so.1", body: "lib", mode: 0o644},
+		{name: "./lib/ollama/libggml.so", link: "libggml.so.1"},
+	})
+	prefix := t.TempDir()
+	if err := ExtractTarGz(archive, prefix); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(prefix, "bin", "ollama"))
+	if err != nil || info.Mode().Perm() != 0o755 {
+		t.Fatalf("unexpected binary %v, error %v", info, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(prefix, "lib", "ollama", "libggml.so")); err != nil || string(data) != "lib" {
+		t.Errorf("unexpected symlink content %q, error %v", data, err)
+	}
+}
+
+func TestExtractTarGz_Illegal(t *testing.T) {
+	testCases := [][]entry{
+		{{name: "../evil", body: "x", mode: 0o644}},
+		{{name: "/etc/evil", body: "x", mode: 0o644}},
+		{{name: "lib/evil", link: "../../evil"}},
+		{{name: "lib/evil", link: "/etc/passwd"}},
+		// 通过指向自身目录的链接跳出安装目录
+		{{name: "self", link: "."}, {name: "self/evil", link: "../evil"}},
+	}
+	for i, entries := range testCases {
+		parent := t.TempDir()
+		prefix := filepath.Join(parent, "prefix")
+		if err := os.Mkdir(prefix, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ExtractTarGz(writeTarGz(t, entries), prefix); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+		if _, err := os.Lstat(filepath.Join(parent, "evil")); err == nil {
+			t.Errorf("case %d: file written outside prefix", i)
+		}
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	header := &zip.FileHeader{Name: "Ollama.app/Contents/MacOS/Ollama"}
+	header.SetMode(0o755)
+	w, _ := zw.CreateHeader(header)
+	_, _ = w.Write([]byte("app"))
+	header = &zip.FileHeader{Name: "Ollama.app/Contents/Current"}
+	header.SetMode(os.ModeSymlink | 0o777)
+	w, _ = zw.CreateHeader(header)
+	_, _ = w.Write([]byte("MacOS"))
+	_ = zw.Close()
+	archive := filepath.Join(t.TempDir(), "Ollama-darwin.zip")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prefix := t.TempDir()
+	if err := ExtractZip(archive, prefix); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(prefix, "Ollama.app", "Contents", "Current", "Ollama")); err != nil || string(data) != "app" {
+		t.Errorf("unexpected content %q, error %v", data, err)
+	}
+}
+
+func TestInstallTarGz(t *testing.T) {
+	prefix := t.TempDir()
+	if err := ExtractTarGz(writeTarGz(t, []entry{
+		{name: "bin/ollama", body: "old", mode: 0o755},
+		{name: "lib/ollama/stale.so", body: "stale", mode: 0o644},
+		{name: "bin/other", body: "keep", mode: 0o755},
+	}), prefix); err != nil {
+		t.Fatal(err)
+	}
+	// 解压失败时保留已安装的版本
+	broken := writeTarGz(t, []entry{
+		{name: "bin/ollama", body: "new", mode: 0o755},
+		{name: "../evil", body: "x", mode: 0o644},
+	})
+	if err := InstallTarGz(broken, prefix); err == nil {
+		t.Fatal("expected error")
+	}
+	if data, _ := os.ReadFile(filepath.Join(prefix, "bin", "ollama")); string(data) != "old" {
+		t.Fatalf("expected old binary kept, got %q", data)
+	}
+
+	archive := writeTarGz(t, []entry{
+		{name: "./bin/ollama", body: "new", mode: 0o755},
+		{name: "./lib/ollama/libggml.so", body: "lib", mode: 0o644},
+	})
+	if err := InstallTarGz(archive, prefix); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(prefix, "bin", "ollama")); string(data) != "new" {
+		t.Errorf("unexpected binary %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(prefix, "lib", "ollama", "stale.so")); !os.IsNotExist(err) {
+		t.Error("expected lib/ollama replaced")
+	}
+	if data, _ := os.ReadFile(filepath.Join(prefix, "bin", "other")); string(data) != "keep" {
+		t.Error("expected unrelated files kept")
+	}
+	if entries, _ := filepath.Glob(filepath.Join(prefix, ".ollama-upgrade-*")); len(entries) != 0 {
+		t.Errorf("staging not removed: %v", entries)
+	}
+}
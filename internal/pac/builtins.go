@@ -0,0 +1,297 @@
+package pac
+
+import (
+	"fmt"
+	"github.com/dop251/goja"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolver 解析主机的 IPv4 地址
+type Resolver interface {
+	LookupIPv4(host string) (net.IP, error)
+}
+
+type dnsResolver struct{}
+
+func (dnsResolver) LookupIPv4(host string) (net.IP, error) {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+	}
+	return nil, fmt.Errorf("no ipv4 address for %s", host)
+}
+
+func (s *Script) resolve(host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	if s.Resolver != nil {
+		return s.Resolver.LookupIPv4(host)
+	}
+	return dnsResolver{}.LookupIPv4(host)
+}
+
+// 注册 PAC 标准函数
+func (s *Script) register() {
+	builtins := map[string]func(call goja.FunctionCall) goja.Value{
+		"isPlainHostName": func(call goja.FunctionCall) goja.Value {
+			return s.vm.ToValue(!strings.Contains(stringArg(call, 0), "."))
+		},
+		"dnsDomainIs": func(call goja.FunctionCall) goja.Value {
+			return s.vm.ToValue(strings.HasSuffix(strings.ToLower(stringArg(call, 0)), strings.ToLower(stringArg(call, 1))))
+		},
+		"localHostOrDomainIs": func(call goja.FunctionCall) goja.Value {
+			host, hostdom := strings.ToLower(stringArg(call, 0)), strings.ToLower(stringArg(call, 1))
+			return s.vm.ToValue(host == hostdom || !strings.Contains(host, ".") && strings.HasPrefix(hostdom, host+"."))
+		},
+		"isResolvable": func(call goja.FunctionCall) goja.Value {
+			_, err := s.resolve(stringArg(call, 0))
+			return s.vm.ToValue(err == nil)
+		},
+		"dnsResolve": func(call goja.FunctionCall) goja.Value {
+			ip, err := s.resolve(stringArg(call, 0))
+			if err != nil {
+				return goja.Null()
+			}
+			return s.vm.ToValue(ip.String())
+		},
+		"isInNet": func(call goja.FunctionCall) goja.Value {
+			ip, err := s.resolve(stringArg(call, 0))
+			if err != nil {
+				return s.vm.ToValue(false)
+			}
+			pattern := net.ParseIP(stringArg(call, 1)).To4()
+			mask := net.ParseIP(stringArg(call, 2)).To4()
+			ip = ip.To4()
+			if ip == nil || pattern == nil || mask == nil {
+				return s.vm.ToValue(false)
+			}
+			return s.vm.ToValue(ip.Mask(net.IPMask(mask)).Equal(pattern.Mask(net.IPMask(mask))))
+		},
+		"myIpAddress": func(goja.FunctionCall) goja.Value {
+			return s.vm.ToValue(myIpAddress())
+		},
+		"dnsDomainLevels": func(call goja.FunctionCall) goja.Value {
+			return s.vm.ToValue(strings.Count(stringArg(call, 0), "."))
+		},
+		"shExpMatch": func(call goja.FunctionCall) goja.Value {
+			return s.vm.ToValue(shExpRegexp(stringArg(call, 1)).MatchString(stringArg(call, 0)))
+		},
+		"weekdayRange": func(call goja.FunctionCall) goja.Value {
+			args, now := s.timeArgs(call)
+			return s.vm.ToValue(weekdayRange(args, now))
+		},
+		"dateRange": func(call goja.FunctionCall) goja.Value {
+			args, now := s.timeArgs(call)
+			return s.vm.ToValue(dateRange(args, now))
+		},
+		"timeRange": func(call goja.FunctionCall) goja.Value {
+			args, now := s.timeArgs(call)
+			return s.vm.ToValue(timeRange(args, now))
+		},
+		"alert": func(goja.FunctionCall) goja.Value {
+			return goja.Undefined()
+		},
+	}
+	for name, fn := range builtins {
+		// 脚本中声明的同名函数会覆盖内置函数
+		_ = s.vm.Set(name, fn)
+	}
+}
+
+func stringArg(call goja.FunctionCall, i int) string {
+	arg := call.Argument(i)
+	if goja.IsUndefined(arg) || goja.IsNull(arg) {
+		return ""
+	}
+	return arg.String()
+}
+
+// shell 通配符转换为正则表达式，* 匹配任意字符，? 匹配单个字符
+func shExpRegexp(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// 本机第一个非回环的 IPv4 地址
+func myIpAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+				if ip4 := ipNet.IP.To4(); ip4 != nil {
+					return ip4.String()
+				}
+			}
+		}
+	}
+	return "127.0.0.1"
+}
+
+// 日期时间函数的参数，最后一个参数为 GMT 时使用 UTC 时间，否则使用本地时间
+func (s *Script) timeArgs(call goja.FunctionCall) ([]string, time.Time) {
+	args := make([]string, len(call.Arguments))
+	for i := range call.Arguments {
+		args[i] = stringArg(call, i)
+	}
+	now := s.now()
+	if len(args) > 0 && strings.EqualFold(args[len(args)-1], "GMT") {
+		return args[:len(args)-1], now.UTC()
+	}
+	return args, now.Local()
+}
+
+var (
+	weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+	months   = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+)
+
+func indexOf(names []string, name string) int {
+	for i, item := range names {
+		if strings.EqualFold(item, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// 判断 value 是否在 [start, end] 范围内，start 大于 end 时跨越周期，如 FRI 至 MON
+func inRange(value, start, end int) bool {
+	if start <= end {
+		return start <= value && value <= end
+	}
+	return value >= start || value <= end
+}
+
+// weekdayRange(wd1 [, wd2] [, "GMT"])
+func weekdayRange(args []string, now time.Time) bool {
+	if len(args) < 1 || len(args) > 2 {
+		return false
+	}
+	start := indexOf(weekdays, args[0])
+	end := start
+	if len(args) == 2 {
+		end = indexOf(weekdays, args[1])
+	}
+	if start < 0 || end < 0 {
+		return false
+	}
+	return inRange(int(now.Weekday()), start, end)
+}
+
+// 日期参数的组成部分，未指定的部分为 -1
+type datePart struct {
+	year, month, day int
+}
+
+func parseDatePart(args []string) (datePart, bool) {
+	part := datePart{year: -1, month: -1, day: -1}
+	for _, arg := range args {
+		if month := indexOf(months, arg); month >= 0 {
+			part.month = month + 1
+			continue
+		}
+		n, err := strconv.Atoi(arg)
+		switch {
+		case err != nil || n < 1:
+			return part, false
+		case n <= 31:
+			part.day = n
+		default:
+			part.year = n
+		}
+	}
+	return part, true
+}
+
+// 按开始日期中指定的部分生成可比较的值
+func (p datePart) key(layout datePart) int {
+	key := 0
+	if layout.year >= 0 {
+		key = p.year
+	}
+	if layout.month >= 0 {
+		key = key*100 + p.month
+	}
+	if layout.day >= 0 {
+		key = key*100 + p.day
+	}
+	return key
+}
+
+// dateRange 支持以下形式，均可追加 "GMT"：
+// day、month、year、day1, day2、month1, month2、year1, year2、
+// day1, month1, day2, month2、month1, year1, month2, year2、
+// day1, month1, year1, day2, month2, year2
+func dateRange(args []string, now time.Time) bool {
+	var startArgs, endArgs []string
+	switch len(args) {
+	case 1:
+		startArgs, endArgs = args, args
+	case 2, 4, 6:
+		startArgs, endArgs = args[:len(args)/2], args[len(args)/2:]
+	default:
+		return false
+	}
+	start, ok1 := parseDatePart(startArgs)
+	end, ok2 := parseDatePart(endArgs)
+	// 开始与结束日期必须指定相同的部分
+	if !ok1 || !ok2 || (start.year < 0) != (end.year < 0) || (start.month < 0) != (end.month < 0) || (start.day < 0) != (end.day < 0) {
+		return false
+	}
+	current := datePart{year: now.Year(), month: int(now.Month()), day: now.Day()}
+	value := current.key(start)
+	if start.year >= 0 {
+		// 指定年份时不会跨越周期
+		return start.key(start) <= value && value <= end.key(start)
+	}
+	return inRange(value, start.key(start), end.key(start))
+}
+
+// timeRange 支持 hour、hour1, hour2、hour1, min1, hour2, min2、
+// hour1, min1, sec1, hour2, min2, sec2 形式，均可追加 "GMT"
+func timeRange(args []string, now time.Time) bool {
+	values := make([]int, len(args))
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return false
+		}
+		values[i] = n
+	}
+	seconds := now.Hour()*3600 + now.Minute()*60 + now.Second()
+	switch len(values) {
+	case 1:
+		return now.Hour() == values[0]
+	case 2:
+		// 结束的小时不包含在内，timeRange(9, 17) 为 9:00 至 16:59:59
+		return inRange(seconds, values[0]*3600, values[1]*3600-1)
+	case 4:
+		return inRange(seconds, values[0]*3600+values[1]*60, values[2]*3600+values[3]*60+59)
+	case 6:
+		return inRange(seconds, values[0]*3600+values[1]*60+values[2], values[3]*3600+values[4]*60+values[5])
+	default:
+		return false
+	}
+}
@@ -0,0 +1,124 @@
+// Package pac 代理自动配置（PAC）文件的解析及执行
+//
+// PAC 文件为 JavaScript 脚本，由 goja 执行，支持完整的 ECMAScript 5.1 语法，
+// 并提供 shExpMatch、dnsDomainIs、isInNet、weekdayRange、dateRange、timeRange 等标准函数。
+// Microsoft 的 *Ex 扩展函数未实现。
+package pac
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dop251/goja"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 函数调用的最大深度，防止递归调用无法结束
+	maxCallDepth = 256
+	// 单次执行的最长时间，防止死循环阻塞请求
+	evalTimeout = 5 * time.Second
+)
+
+// Script 解析后的 PAC 脚本
+type Script struct {
+	vm   *goja.Runtime
+	find goja.Callable
+	// goja.Runtime 不支持并发执行，执行时加锁
+	lock sync.Mutex
+	// Resolver 解析主机地址，为空时使用系统 DNS
+	Resolver Resolver
+	// 当前时间及执行超时，测试时替换
+	now     func() time.Time
+	timeout time.Duration
+}
+
+// Parse 解析并执行 PAC 脚本，脚本中必须声明 FindProxyForURL(url, host) 函数
+func Parse(src string) (*Script, error) {
+	program, err := goja.Compile("proxy.pac", src, false)
+	if err != nil {
+		return nil, err
+	}
+	s := &Script{vm: goja.New(), now: time.Now, timeout: evalTimeout}
+	s.vm.SetMaxCallStackSize(maxCallDepth)
+	s.register()
+	// 执行全局变量的初始化
+	if _, err := s.run(func() (goja.Value, error) { return s.vm.RunProgram(program) }); err != nil {
+		return nil, err
+	}
+	find, ok := goja.AssertFunction(s.vm.Get("FindProxyForURL"))
+	if !ok {
+		return nil, errors.New("FindProxyForURL is not defined")
+	}
+	s.find = find
+	return s, nil
+}
+
+// FindProxyForURL 执行脚本，返回形如 "PROXY host:port; DIRECT" 的结果
+func (s *Script) FindProxyForURL(u *url.URL) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	value, err := s.run(func() (goja.Value, error) {
+		return s.find(goja.Undefined(), s.vm.ToValue(u.String()), s.vm.ToValue(u.Hostname()))
+	})
+	if err != nil {
+		return "", err
+	}
+	result, ok := value.Export().(string)
+	if !ok {
+		return "", fmt.Errorf("FindProxyForURL returned %s, expected string", value)
+	}
+	return result, nil
+}
+
+// 执行超时后中断脚本
+func (s *Script) run(fn func() (goja.Value, error)) (goja.Value, error) {
+	timer := time.AfterFunc(s.timeout, func() {
+		s.vm.Interrupt(fmt.Errorf("pac script timed out after %s", s.timeout))
+	})
+	defer func() {
+		timer.Stop()
+		s.vm.ClearInterrupt()
+	}()
+	return fn()
+}
+
+// Proxy 执行脚本并返回第一个代理，DIRECT 时返回 nil
+func (s *Script) Proxy(u *url.URL) (*url.URL, error) {
+	result, err := s.FindProxyForURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return ParseResult(result)
+}
+
+// ParseResult 解析 FindProxyForURL 的返回结果，使用第一个可用的代理
+func ParseResult(result string) (*url.URL, error) {
+	for _, item := range strings.Split(result, ";") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+		var scheme string
+		switch strings.ToUpper(fields[0]) {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS5":
+			scheme = "socks5"
+		default:
+			// SOCKS4 等不支持的类型尝试下一项
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid pac result %q", item)
+		}
+		return &url.URL{Scheme: scheme, Host: fields[1]}, nil
+	}
+	return nil, fmt.Errorf("no usable proxy in pac result %q", result)
+}
@@ -0,0 +1,253 @@
+package pac
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type staticResolver map[string]string
+
+func (r staticResolver) LookupIPv4(host string) (net.IP, error) {
+	if ip, ok := r[host]; ok {
+		return net.ParseIP(ip).To4(), nil
+	}
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+const script = `
+// 公司网络代理配置
+var proxy = "PROXY proxy.corp.local:3128";
+
+function isLan(host) {
+    return isInNet(host, "10.0.0.0", "255.0.0.0") || isInNet(host, "192.168.0.0", "255.255.0.0");
+}
+
+function FindProxyForURL(url, host) {
+    host = host.toLowerCase();
+    if (isPlainHostName(host) || dnsDomainIs(host, ".corp.local") || isLan(host))
+        return "DIRECT";
+    /* 模型仓库走 SOCKS */
+    if (shExpMatch(url, "https://registry.ollama.ai/*")) {
+        return "SOCKS5 127.0.0.1:1080; DIRECT";
+    } else if (url.substring(0, 5) == "http:" && host.indexOf("github") != -1) {
+        return proxy + "; DIRECT";
+    }
+    return dnsDomainLevels(host) > 1 ? proxy : "HTTPS secure.corp.local:443";
+}
+`
+
+func TestScript_Proxy(t *testing.T) {
+	s, err := Parse(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Resolver = staticResolver{"gpu.lan": "192.168.1.20", "ollama.com": "34.36.133.15"}
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{"http://localhost:11434/api/tags", ""},
+		{"http://GPU.lan:11434/api/tags", ""},
+		{"https://wiki.corp.local/", ""},
+		{"https://registry.ollama.ai/v2/library/qwen2/manifests/latest", "socks5://127.0.0.1:1080"},
+		{"http://api.github.com/repos", "http://proxy.corp.local:3128"},
+		{"https://ollama.com/library", "https://secure.corp.local:443"},
+		{"https://www.ollama.com/library", "http://proxy.corp.local:3128"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			u, _ := url.Parse(tc.url)
+			proxy, err := s.Proxy(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := ""
+			if proxy != nil {
+				actual = proxy.String()
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+	}{
+		{"missing function", `function other(url, host) { return "DIRECT"; }`},
+		{"unterminated string", `function FindProxyForURL(url, host) { return "DIRECT; }`},
+		{"syntax", `function FindProxyForURL(url, host) { return ; ) }`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(tc.src); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestScript_RuntimeError(t *testing.T) {
+	s, err := Parse(`function FindProxyForURL(url, host) { return f(host); } function f(h) { return f(h); }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://ollama.com")
+	if _, err := s.Proxy(u); err == nil {
+		t.Error("expected call depth error")
+	}
+	s, err = Parse(`function FindProxyForURL(url, host) { for (;;) {} }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.timeout = 100 * time.Millisecond
+	if _, err := s.Proxy(u); err == nil {
+		t.Error("expected timeout error")
+	}
+	// 中断后脚本仍可继续执行
+	if _, err := s.Proxy(u); err == nil {
+		t.Error("expected timeout error")
+	}
+}
+
+func TestScript_Syntax(t *testing.T) {
+	s, err := Parse(`
+var rules = [
+    { pattern: /^([a-z0-9-]+\.)*ollama\.(com|ai)$/, proxy: "PROXY a:8080" },
+    { pattern: /\.cn$/, proxy: "DIRECT" }
+];
+
+function FindProxyForURL(url, host) {
+    for (var i = 0; i < rules.length; i++) {
+        if (rules[i].pattern.test(host))
+            return rules[i].proxy;
+    }
+    switch (url.split(":")[0]) {
+    case "https":
+        return "HTTPS b:443";
+    default:
+        return "SOCKS c:1080";
+    }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{"https://registry.ollama.ai/v2/", "http://a:8080"},
+		{"https://ollama.com/library", "http://a:8080"},
+		{"https://gitee.cn/", ""},
+		{"https://github.com/", "https://b:443"},
+		{"http://github.com/", "socks5://c:1080"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			u, _ := url.Parse(tc.url)
+			proxy, err := s.Proxy(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := ""
+			if proxy != nil {
+				actual = proxy.String()
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestScript_TimeFunctions(t *testing.T) {
+	s, err := Parse(`
+function FindProxyForURL(url, host) {
+    if (weekdayRange("SAT", "GMT") && dateRange(1, "JUN", 30, "JUN", "GMT") && timeRange(9, 17, "GMT"))
+        return "PROXY a:8080";
+    return "DIRECT";
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-06-15 为星期六
+	now := time.Date(2024, time.June, 15, 10, 30, 15, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	u, _ := url.Parse("https://ollama.com")
+	if actual, err := s.FindProxyForURL(u); err != nil || actual != "PROXY a:8080" {
+		t.Errorf("expected PROXY a:8080, got %q %v", actual, err)
+	}
+	s.now = func() time.Time { return now.Add(24 * time.Hour) }
+	if actual, err := s.FindProxyForURL(u); err != nil || actual != "DIRECT" {
+		t.Errorf("expected DIRECT, got %q %v", actual, err)
+	}
+	testCases := []struct {
+		fn       func([]string, time.Time) bool
+		args     []string
+		expected bool
+	}{
+		{weekdayRange, []string{"SAT"}, true},
+		{weekdayRange, []string{"MON", "FRI"}, false},
+		{weekdayRange, []string{"FRI", "MON"}, true},
+		{weekdayRange, []string{"XYZ"}, false},
+		{dateRange, []string{"15"}, true},
+		{dateRange, []string{"JUN"}, true},
+		{dateRange, []string{"2023"}, false},
+		{dateRange, []string{"1", "15"}, true},
+		{dateRange, []string{"NOV", "FEB"}, false},
+		{dateRange, []string{"MAY", "AUG"}, true},
+		{dateRange, []string{"1", "JUN", "14", "JUN"}, false},
+		{dateRange, []string{"JUN", "2023", "JAN", "2025"}, true},
+		{dateRange, []string{"1", "JAN", "2024", "15", "JUN", "2024"}, true},
+		{dateRange, []string{"1", "JUN"}, false},
+		{timeRange, []string{"10"}, true},
+		{timeRange, []string{"9", "10"}, false},
+		{timeRange, []string{"9", "11"}, true},
+		{timeRange, []string{"22", "11"}, true},
+		{timeRange, []string{"10", "0", "10", "30"}, true},
+		{timeRange, []string{"10", "30", "10", "10", "30", "20"}, true},
+		{timeRange, []string{"10", "30", "16", "10", "30", "20"}, false},
+	}
+	for _, tc := range testCases {
+		if actual := tc.fn(tc.args, now); actual != tc.expected {
+			t.Errorf("%v: expected %v, got %v", tc.args, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	testCases := []struct {
+		result   string
+		expected string
+		err      bool
+	}{
+		{"DIRECT", "", false},
+		{"PROXY a:8080; DIRECT", "http://a:8080", false},
+		{"SOCKS4 a:1080; SOCKS b:1080", "socks5://b:1080", false},
+		{"PROXY", "", true},
+		{"", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.result, func(t *testing.T) {
+			proxy, err := ParseResult(tc.result)
+			if (err != nil) != tc.err {
+				t.Fatalf("unexpected error %v", err)
+			}
+			actual := ""
+			if proxy != nil {
+				actual = proxy.String()
+			}
+			if actual != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 type Release interface {
@@ -14,10 +15,28 @@ type Release interface {
 }
 
 type Item struct {
-	Name    string `json:"name"`
-	TagName string `json:"tag_name"`
-	Body    string `json:"body"`
-	Url     string `json:"url"`
+	Name    string   `json:"name"`
+	TagName string   `json:"tag_name"`
+	Body    string   `json:"body"`
+	Url     string   `json:"url"`
+	Assets  []*Asset `json:"assets,omitempty"`
+}
+
+// Asset 发布附带的文件
+type Asset struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Url  string `json:"browser_download_url"`
+}
+
+// Asset 按名称查找附带的文件，忽略大小写
+func (i *Item) Asset(name string) *Asset {
+	for _, asset := range i.Assets {
+		if strings.EqualFold(asset.Name, name) {
+			return asset
+		}
+	}
+	return nil
 }
 
 type GiteeRelease struct {